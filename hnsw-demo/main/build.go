@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/dataset"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// runBuild builds an index from a dataset file. Vector i of the dataset
+// gets id start+i
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	input := fs.String("input", "", "dataset file (.fvecs, .npy or .csv)")
+	output := fs.String("output", "index.hnsw", "index file to write")
+	metric := fs.String("metric", distance.Euclidean, "distance metric")
+	m := fs.Int("m", 16, "connections per element")
+	maxM := fs.Int("max-m", 0, "maximum connections per element, defaults to 2*m")
+	efConstruction := fs.Int("ef-construction", 100, "candidate list size during construction")
+	start := fs.Int("start-id", 0, "id of the first vector")
+	format := fs.String("format", "", "storage format (gob or json), defaults to the output extension")
+	description := fs.String("description", "", "description stored in the metadata")
+	seed := fs.Int64("seed", 0, "seed of the level generator, random if 0")
+	bulk := fs.Bool("bulk", false, "bulk load with parallel workers instead of inserting one by one")
+	workers := fs.Int("workers", 0, "bulk load workers, defaults to GOMAXPROCS")
+	order := fs.String("order", "input", "bulk load order (input, shuffle or cluster)")
+	fs.Parse(args)
+
+	if *input == "" {
+		return fmt.Errorf("-input is required")
+	}
+	if *maxM == 0 {
+		*maxM = 2 * *m
+	}
+	storageFormat := storage.FormatFromFilename(*output)
+	if *format != "" {
+		var err error
+		if storageFormat, err = storage.ParseFormat(*format); err != nil {
+			return err
+		}
+	}
+
+	bulkOrder, err := algorithm.ParseBulkOrder(*order)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(*m, *maxM, *efConstruction, false)
+	if err != nil {
+		return err
+	}
+	cfg.Seed = *seed
+	index, err := algorithm.New(cfg, *metric)
+	if err != nil {
+		return err
+	}
+
+	vectors, err := dataset.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Read %d vectors from %s\n", len(vectors), *input)
+
+	began := time.Now()
+	if *bulk {
+		records := make([]algorithm.Record, len(vectors))
+		for i, v := range vectors {
+			records[i] = algorithm.Record{ID: *start + i, Vector: v}
+		}
+		opts := algorithm.BulkOptions{Workers: *workers, Order: bulkOrder}
+		if err := index.BulkLoad(context.Background(), records, opts); err != nil {
+			return err
+		}
+	} else {
+		for i, v := range vectors {
+			if err := index.Insert(*start+i, v); err != nil {
+				return fmt.Errorf("vector %d: %v", i, err)
+			}
+			if (i+1)%10000 == 0 {
+				fmt.Printf("  inserted %d/%d\n", i+1, len(vectors))
+			}
+		}
+	}
+	elapsed := time.Since(began)
+	fmt.Printf("Built index in %v (%.0f inserts/s)\n", elapsed.Round(time.Millisecond), float64(len(vectors))/elapsed.Seconds())
+
+	if err := index.SaveAs(*output, *description, storageFormat); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s\n", *output)
+	return nil
+}
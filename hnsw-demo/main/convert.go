@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+)
+
+// runConvert rewrites an index file in another storage format, keeping its
+// metadata
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	input := fs.String("input", "", "index file to read")
+	output := fs.String("output", "", "index file to write")
+	format := fs.String("format", "", "storage format (gob or json), defaults to the output extension")
+	fs.Parse(args)
+
+	if *input == "" || *output == "" {
+		return fmt.Errorf("-input and -output are required")
+	}
+	storageFormat := storage.FormatFromFilename(*output)
+	if *format != "" {
+		var err error
+		if storageFormat, err = storage.ParseFormat(*format); err != nil {
+			return err
+		}
+	}
+
+	data, err := storage.Load(*input)
+	if err != nil {
+		return err
+	}
+	inputFormat, err := storage.DetectFormat(*input)
+	if err != nil {
+		return err
+	}
+	if err := storage.SaveAs(*output, data, storageFormat); err != nil {
+		return err
+	}
+	fmt.Printf("Converted %s (%s) to %s (%s)\n", *input, inputFormat, *output, storageFormat)
+	return nil
+}
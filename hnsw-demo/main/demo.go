@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// runDemo inserts random vectors into a fresh index and queries it
+func runDemo(args []string) error {
+	// Create configuration
+	cfg := config.Config{
+		M:              16,
+		MaxM:           32,
+		EfConstruction: 100,
+		ML:             1.0 / float64(16),
+		DelayRebuild:   false,
+	}
+
+	// Create HNSW index
+	index, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %v", err)
+	}
+
+	// Set random seed
+	rand.Seed(time.Now().UnixNano())
+
+	// Generate and insert random vectors
+	dim := 3
+	numVectors := 1000
+
+	fmt.Println("Inserting vectors...")
+	start := time.Now()
+	for id := 0; id < numVectors; id++ {
+		vec := make([]float64, dim)
+		for j := 0; j < dim; j++ {
+			vec[j] = rand.Float64()
+		}
+		if err := index.Insert(id, vec); err != nil {
+			fmt.Printf("Failed to insert vector %d: %v\n", id, err)
+		}
+	}
+	fmt.Printf("Insertion took: %v\n", time.Since(start))
+
+	// Perform search
+	fmt.Println("\nPerforming search...")
+	query := make([]float64, dim)
+	for i := 0; i < dim; i++ {
+		query[i] = rand.Float64()
+	}
+
+	k := 10
+	ef := 50
+	start = time.Now()
+	results, err := index.Search(context.Background(), query, algorithm.SearchOptions{
+		K:              k,
+		Ef:             ef,
+		IncludeVectors: true,
+	})
+	searchTime := time.Since(start)
+	if err != nil {
+		return fmt.Errorf("search failed: %v", err)
+	}
+
+	// Print results
+	fmt.Printf("\nSearch took: %v\n", searchTime)
+	fmt.Printf("Query vector: %v\n", query)
+	fmt.Printf("\nNearest %d neighbors:\n", k)
+	for i, result := range results {
+		fmt.Printf("%d. ID: %d, Distance: %.4f, Vector: %v\n",
+			i+1, result.ID, result.Distance, result.Vector)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/rpc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9090", "address to listen on")
+	indexFile := flag.String("index", "data/index.hnsw", "index file, created on shutdown if missing")
+	metric := flag.String("metric", distance.Euclidean, "distance metric of a new index")
+	flag.Parse()
+
+	index, err := algorithm.Load(*indexFile)
+	if errors.Is(err, os.ErrNotExist) {
+		index, err = algorithm.New(config.NewDefaultConfig(), *metric)
+	}
+	if err != nil {
+		log.Fatalf("Failed to open index: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := grpc.NewServer()
+	rpc.Register(srv, index)
+
+	// Stop on SIGINT/SIGTERM and save the index
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		log.Println("Shutting down")
+		srv.GracefulStop()
+	}()
+
+	log.Printf("Serving %d elements on %s", index.Len(), *addr)
+	if err := srv.Serve(listener); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+	if err := index.Save(*indexFile, ""); err != nil {
+		log.Fatalf("Failed to save index: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// indexFlag parses the -index flag shared by the inspection commands
+func indexFlag(name string, args []string) string {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	indexFile := fs.String("index", "index.hnsw", "index file")
+	fs.Parse(args)
+	return *indexFile
+}
+
+// runInfo prints the metadata of an index file
+func runInfo(args []string) error {
+	indexFile := indexFlag("info", args)
+	info, err := storage.GetIndexInfo(indexFile)
+	if err != nil {
+		return err
+	}
+	format, err := storage.DetectFormat(indexFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("File:        %s (%s)\n", indexFile, format)
+	fmt.Printf("Version:     %s\n", info.Version)
+	fmt.Printf("Created:     %s\n", info.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("Nodes:       %d\n", info.NodesCount)
+	fmt.Printf("Max level:   %d\n", info.MaxLevel)
+	fmt.Printf("Metric:      %s\n", info.Metric)
+	fmt.Printf("Sparse:      %v\n", info.Sparse)
+	fmt.Printf("Config:      %s\n", info.Config)
+	fmt.Printf("Seed:        %d\n", info.Seed)
+	if info.DefaultEf > 0 {
+		fmt.Printf("Default ef:  %d\n", info.DefaultEf)
+	}
+	if info.Description != "" {
+		fmt.Printf("Description: %s\n", info.Description)
+	}
+	return nil
+}
+
+// runStats prints per-level node counts, degree histograms and the health
+// of the graph
+func runStats(args []string) error {
+	index, err := algorithm.Load(indexFlag("stats", args))
+	if err != nil {
+		return err
+	}
+
+	stats := index.Stats()
+	fmt.Printf("Nodes: %d, entry point: %d, max level: %d\n", stats.Nodes, stats.EntryPoint, stats.MaxLevel)
+	fmt.Printf("Unreachable: %d, memory: %.1f MiB\n", stats.Unreachable, float64(stats.MemoryBytes)/(1<<20))
+
+	for level := len(stats.Levels) - 1; level >= 0; level-- {
+		ls := stats.Levels[level]
+		if ls.Nodes == 0 {
+			continue
+		}
+		fmt.Printf("\nLevel %d: %d nodes, degree min %d, mean %.2f, max %d, unreachable %d\n",
+			level, ls.Nodes, ls.MinDegree, ls.MeanDegree, ls.MaxDegree, ls.Unreachable)
+		printHistogram(ls.Histogram, ls.Nodes)
+	}
+	return nil
+}
+
+// printHistogram prints how many of count nodes have each degree
+func printHistogram(degrees map[int]int, count int) {
+	values := make([]int, 0, len(degrees))
+	for d := range degrees {
+		values = append(values, d)
+	}
+	sort.Ints(values)
+
+	for _, d := range values {
+		n := degrees[d]
+		bar := n * 40 / count
+		if bar == 0 {
+			bar = 1
+		}
+		fmt.Printf("  %3d | %-40s %d\n", d, repeat('#', bar), n)
+	}
+}
+
+func repeat(c byte, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = c
+	}
+	return string(b)
+}
+
+// runVerify checks the graph structure of an index file, printing every
+// problem found
+func runVerify(args []string) error {
+	index, err := algorithm.Load(indexFlag("verify", args))
+	if err != nil {
+		return err
+	}
+
+	report := index.Verify()
+	fmt.Println(report)
+	if !report.OK() {
+		return fmt.Errorf("%d problems found", len(report.Problems))
+	}
+	return nil
+}
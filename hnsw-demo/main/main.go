@@ -1,76 +1,64 @@
+// Command main builds, queries and inspects HNSW indexes.
+//
+// Usage:
+//
+//	go run ./main <command> [flags]
+//
+// Run a command with -h to list its flags.
 package main
 
 import (
 	"fmt"
-	"math/rand"
-	"time"
-
-	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
-	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
-	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"os"
 )
 
-func main() {
-	// Create configuration
-	cfg := config.Config{
-		M:              16,
-		MaxM:           32,
-		EfConstruction: 100,
-		ML:             1.0 / float64(16),
-		DelayRebuild:   false,
-	}
-
-	// Create HNSW index
-	index, err := algorithm.New(cfg, distance.Euclidean)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create index: %v", err))
-	}
-
-	// Set random seed
-	rand.Seed(time.Now().UnixNano())
+// command is a subcommand of the tool
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	// Generate random vectors
-	dim := 3
-	numVectors := 1000
-	vectors := make(map[int][]float64)
+var commands = []command{
+	{"build", "build an index from an fvecs, npy or CSV dataset", runBuild},
+	{"query", "search an index for one vector or a file of queries", runQuery},
+	{"tune", "find the smallest ef reaching a target recall", runTune},
+	{"sweep", "compare construction parameters on a sample of a dataset", runSweep},
+	{"info", "print the metadata of an index file", runInfo},
+	{"stats", "print level and degree histograms of an index", runStats},
+	{"verify", "check the structure of an index", runVerify},
+	{"repair", "reconnect unreachable nodes of an index", runRepair},
+	{"merge", "merge index files with disjoint ids", runMerge},
+	{"convert", "rewrite an index in another storage format", runConvert},
+	{"demo", "insert random vectors and run one query", runDemo},
+}
 
-	for i := 0; i < numVectors; i++ {
-		vec := make([]float64, dim)
-		for j := 0; j < dim; j++ {
-			vec[j] = rand.Float64()
-		}
-		vectors[i] = vec
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: hnsw <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
 	}
+}
 
-	// Insert vectors
-	fmt.Println("Inserting vectors...")
-	start := time.Now()
-	for id, vec := range vectors {
-		if err := index.Insert(id, vec); err != nil {
-			fmt.Printf("Failed to insert vector %d: %v\n", id, err)
-		}
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
 	}
-	fmt.Printf("Insertion took: %v\n", time.Since(start))
 
-	// Perform search
-	fmt.Println("\nPerforming search...")
-	query := make([]float64, dim)
-	for i := 0; i < dim; i++ {
-		query[i] = rand.Float64()
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	k := 10
-	ef := 50
-	start = time.Now()
-	results, distances := index.KNNSearchWithDistances(query, k, ef)
-	searchTime := time.Since(start)
-
-	// Print results
-	fmt.Printf("\nSearch took: %v\n", searchTime)
-	fmt.Printf("Query vector: %v\n", query)
-	fmt.Printf("\nNearest %d neighbors:\n", k)
-	for i, id := range results {
-		fmt.Printf("%d. ID: %d, Distance: %.4f, Vector: %v\n",
-			i+1, id, distances[i], vectors[id])
-	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// runMerge merges index files with disjoint ids into one. The merged index
+// takes the config of the first file
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := fs.String("output", "merged.hnsw", "index file to write")
+	description := fs.String("description", "", "description stored in the metadata")
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) < 2 {
+		return fmt.Errorf("merge needs at least two index files")
+	}
+
+	merged, err := algorithm.Load(files[0])
+	if err != nil {
+		return err
+	}
+	for _, file := range files[1:] {
+		index, err := algorithm.Load(file)
+		if err != nil {
+			return err
+		}
+		if merged, err = algorithm.Merge(merged, index); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	fmt.Printf("Merged %d files into %d elements\n", len(files), merged.Len())
+
+	if err := merged.Save(*output, *description); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s\n", *output)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/dataset"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// runQuery searches an index for the vector given on the command line or
+// for every vector of a query file
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	indexFile := fs.String("index", "index.hnsw", "index file")
+	vector := fs.String("vector", "", "comma-separated query vector")
+	queries := fs.String("queries", "", "file of query vectors (.fvecs, .npy or .csv)")
+	k := fs.Int("k", 10, "number of neighbors")
+	ef := fs.Int("ef", 0, "candidate list size, defaults to the tuned ef of the index or 2*k")
+	fs.Parse(args)
+
+	var vectors [][]float64
+	switch {
+	case *vector != "" && *queries != "":
+		return fmt.Errorf("use either -vector or -queries")
+	case *vector != "":
+		v, err := dataset.ParseVector(strings.Split(*vector, ","))
+		if err != nil {
+			return err
+		}
+		vectors = [][]float64{v}
+	case *queries != "":
+		var err error
+		if vectors, err = dataset.ReadFile(*queries); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("-vector or -queries is required")
+	}
+
+	index, err := algorithm.Load(*indexFile)
+	if err != nil {
+		return err
+	}
+
+	opts := algorithm.SearchOptions{K: *k, Ef: *ef}
+	var elapsed time.Duration
+	for i, q := range vectors {
+		began := time.Now()
+		results, err := index.Search(context.Background(), q, opts)
+		elapsed += time.Since(began)
+		if err != nil {
+			return fmt.Errorf("query %d: %v", i, err)
+		}
+
+		fmt.Printf("query %d:\n", i)
+		for rank, result := range results {
+			fmt.Printf("  %d. id=%d distance=%.6f\n", rank+1, result.ID, result.Distance)
+		}
+	}
+
+	if len(vectors) > 1 {
+		fmt.Printf("%d queries in %v (%.0f queries/s)\n",
+			len(vectors), elapsed.Round(time.Microsecond), float64(len(vectors))/elapsed.Seconds())
+	}
+	return nil
+}
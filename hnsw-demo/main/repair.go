@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// runRepair reconnects the unreachable nodes of an index file and writes
+// the result back, or to -output
+func runRepair(args []string) error {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	indexFile := fs.String("index", "index.hnsw", "index file")
+	output := fs.String("output", "", "index file to write, defaults to -index")
+	fs.Parse(args)
+
+	if *output == "" {
+		*output = *indexFile
+	}
+
+	info, err := storage.GetIndexInfo(*indexFile)
+	if err != nil {
+		return err
+	}
+	index, err := algorithm.Load(*indexFile)
+	if err != nil {
+		return err
+	}
+
+	report := index.Repair()
+	fmt.Printf("Repair: %s\n", report)
+	if err := index.Save(*output, info.Description); err != nil {
+		return err
+	}
+	if report.Remaining > 0 {
+		return fmt.Errorf("%d node-layer pairs are still unreachable", report.Remaining)
+	}
+	fmt.Printf("Saved %s\n", *output)
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/collection"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/server"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dataDir := flag.String("data", "data", "directory holding the collections")
+	flag.Parse()
+
+	manager, err := collection.Open(*dataDir)
+	if err != nil {
+		log.Fatalf("Failed to open data directory: %v", err)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(manager),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Shut down on SIGINT/SIGTERM and snapshot every collection
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Serving %d collections from %s on %s", len(manager.List()), *dataDir, *addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown failed: %v", err)
+	}
+	if err := manager.SaveAll(); err != nil {
+		log.Fatalf("Failed to save collections: %v", err)
+	}
+}
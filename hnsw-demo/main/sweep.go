@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/dataset"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/sweep"
+)
+
+// runSweep builds indexes over a sample of a dataset for a grid or a random
+// selection of construction parameters and prints their measurements and
+// Pareto frontier
+func runSweep(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	input := fs.String("input", "", "dataset file (.fvecs, .npy or .csv)")
+	queries := fs.String("queries", "", "file of query vectors, defaults to vectors held out of the sample")
+	sample := fs.Int("sample", 10000, "number of dataset vectors to index, 0 for all")
+	numQueries := fs.Int("num-queries", 100, "number of queries held out when -queries is not set")
+	metric := fs.String("metric", distance.Euclidean, "distance metric")
+	ms := fs.String("m", "8,16,32", "comma-separated values of M")
+	maxMs := fs.String("max-m", "", "comma-separated values of MaxM, defaults to 2*M")
+	efs := fs.String("ef-construction", "100,200", "comma-separated values of EfConstruction")
+	random := fs.Int("random", 0, "number of random combinations to try instead of the whole grid")
+	k := fs.Int("k", 10, "number of neighbors")
+	ef := fs.Int("ef", 0, "candidate list size of the queries, defaults to 2*k")
+	seed := fs.Int64("seed", 1, "seed of the sample, the random search and the level generators")
+	fs.Parse(args)
+
+	if *input == "" {
+		return fmt.Errorf("-input is required")
+	}
+	var space sweep.Space
+	var err error
+	if space.M, err = parseInts(*ms); err != nil {
+		return fmt.Errorf("-m: %v", err)
+	}
+	if space.MaxM, err = parseInts(*maxMs); err != nil {
+		return fmt.Errorf("-max-m: %v", err)
+	}
+	if space.EfConstruction, err = parseInts(*efs); err != nil {
+		return fmt.Errorf("-ef-construction: %v", err)
+	}
+
+	vectors, err := dataset.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	rng.Shuffle(len(vectors), func(i, j int) { vectors[i], vectors[j] = vectors[j], vectors[i] })
+
+	var queryVectors [][]float64
+	if *queries != "" {
+		if queryVectors, err = dataset.ReadFile(*queries); err != nil {
+			return err
+		}
+	} else {
+		n := min(*numQueries, len(vectors)/2)
+		queryVectors, vectors = vectors[:n], vectors[n:]
+	}
+	if *sample > 0 && len(vectors) > *sample {
+		vectors = vectors[:*sample]
+	}
+
+	params := sweep.Grid(space)
+	if *random > 0 {
+		params = sweep.Random(space, *random, rng)
+	}
+	fmt.Printf("Sweeping %d combinations over %d vectors with %d queries\n\n", len(params), len(vectors), len(queryVectors))
+
+	results, err := sweep.Run(context.Background(), vectors, queryVectors, params, sweep.Options{
+		Metric: *metric,
+		K:      *k,
+		Ef:     *ef,
+		Seed:   *seed,
+	})
+	if err != nil {
+		return err
+	}
+
+	frontier := make(map[sweep.Params]bool)
+	for _, r := range sweep.Frontier(results) {
+		frontier[r.Params] = true
+	}
+	fmt.Printf("%5s %5s %7s %12s %10s %8s %10s  %s\n", "M", "MaxM", "EfCons", "build", "memory", "recall", "QPS", "pareto")
+	for _, r := range results {
+		mark := ""
+		if frontier[r.Params] {
+			mark = "*"
+		}
+		fmt.Printf("%5d %5d %7d %12v %8.1fMB %8.4f %10.0f  %s\n",
+			r.Params.M, r.Params.MaxM, r.Params.EfConstruction, r.BuildTime.Round(time.Millisecond),
+			float64(r.MemoryBytes)/(1<<20), r.Recall, r.QPS, mark)
+	}
+	return nil
+}
+
+// parseInts parses a comma-separated list of integers; an empty string is
+// an empty list
+func parseInts(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, ",")
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
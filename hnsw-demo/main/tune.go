@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/dataset"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// runTune finds the smallest ef reaching a target recall on a file of
+// sample queries and saves it as the default ef of the index
+func runTune(args []string) error {
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	indexFile := fs.String("index", "index.hnsw", "index file")
+	queries := fs.String("queries", "", "file of sample query vectors (.fvecs, .npy or .csv)")
+	k := fs.Int("k", 10, "number of neighbors")
+	recall := fs.Float64("recall", 0.95, "target recall")
+	fs.Parse(args)
+
+	if *queries == "" {
+		return fmt.Errorf("-queries is required")
+	}
+	vectors, err := dataset.ReadFile(*queries)
+	if err != nil {
+		return err
+	}
+
+	info, err := storage.GetIndexInfo(*indexFile)
+	if err != nil {
+		return err
+	}
+	index, err := algorithm.Load(*indexFile)
+	if err != nil {
+		return err
+	}
+
+	tuning, err := index.TuneEf(context.Background(), vectors, *k, *recall)
+	for _, trial := range tuning.Trials {
+		fmt.Printf("  ef %5d: recall %.4f\n", trial.Ef, trial.Recall)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := index.Save(*indexFile, info.Description); err != nil {
+		return err
+	}
+	fmt.Printf("Default ef of %s is now %d (recall %.4f at k=%d)\n", *indexFile, tuning.Ef, tuning.Recall, *k)
+	return nil
+}
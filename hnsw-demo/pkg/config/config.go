@@ -21,6 +21,11 @@ type Config struct {
 
 	// Whether to delay index rebuilding after deletions
 	DelayRebuild bool
+
+	// Seed of the index's level generator. Indexes built with the same
+	// nonzero seed from the same sequence of inserts are identical; zero
+	// picks a random seed
+	Seed int64
 }
 
 // NewDefaultConfig creates a Config with default values
@@ -76,6 +81,6 @@ func (c Config) Validate() error {
 
 // String returns a string representation of the config
 func (c Config) String() string {
-	return fmt.Sprintf("Config{M: %d, MaxM: %d, EfConstruction: %d, ML: %f, DelayRebuild: %v}",
-		c.M, c.MaxM, c.EfConstruction, c.ML, c.DelayRebuild)
+	return fmt.Sprintf("Config{M: %d, MaxM: %d, EfConstruction: %d, ML: %f, DelayRebuild: %v, Seed: %d}",
+		c.M, c.MaxM, c.EfConstruction, c.ML, c.DelayRebuild, c.Seed)
 }
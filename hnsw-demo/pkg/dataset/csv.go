@@ -0,0 +1,51 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCSV reads one vector per row of comma-separated numbers. A first row
+// that does not parse as numbers is treated as a header and skipped
+func ReadCSV(r io.Reader) ([][]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var vectors [][]float64
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+
+		v, err := ParseVector(record)
+		if err != nil {
+			if row == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, checkDimensions(vectors)
+}
+
+// ParseVector parses fields as a vector
+func ParseVector(fields []string) ([]float64, error) {
+	v := make([]float64, len(fields))
+	for i, field := range fields {
+		x, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", field)
+		}
+		v[i] = x
+	}
+	return v, nil
+}
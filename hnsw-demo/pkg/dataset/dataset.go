@@ -0,0 +1,69 @@
+// Package dataset reads vector datasets in the formats commonly used for
+// nearest neighbor benchmarks
+package dataset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFile reads the vectors stored in filename, choosing the reader from
+// the extension: .fvecs, .npy or .csv
+func ReadFile(filename string) ([][]float64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer file.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".fvecs":
+		return ReadFvecs(file)
+	case ".npy":
+		return ReadNpy(file)
+	case ".csv":
+		return ReadCSV(file)
+	default:
+		return nil, fmt.Errorf("unsupported dataset format %q", ext)
+	}
+}
+
+// checkDimensions reports an error unless all vectors share one dimension
+func checkDimensions(vectors [][]float64) error {
+	for i, v := range vectors {
+		if len(v) != len(vectors[0]) {
+			return fmt.Errorf("vector %d has dimension %d, expected %d", i, len(v), len(vectors[0]))
+		}
+	}
+	return nil
+}
+
+// readChunk bounds the values readFloats allocates for ahead of reading them
+const readChunk = 4096
+
+// readFloats reads n little-endian floats of size 4 or 8 bytes from r.
+// Memory grows with the values actually read, so a corrupt count fails at
+// the end of the data rather than allocating for it up front
+func readFloats(r io.Reader, n, size int) ([]float64, error) {
+	v := make([]float64, 0, min(n, readChunk))
+	buf := make([]byte, size*min(n, readChunk))
+	for len(v) < n {
+		chunk := buf[:size*min(n-len(v), readChunk)]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		for j := 0; j < len(chunk); j += size {
+			if size == 4 {
+				v = append(v, float64(math.Float32frombits(binary.LittleEndian.Uint32(chunk[j:]))))
+			} else {
+				v = append(v, math.Float64frombits(binary.LittleEndian.Uint64(chunk[j:])))
+			}
+		}
+	}
+	return v, nil
+}
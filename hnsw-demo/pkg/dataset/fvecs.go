@@ -0,0 +1,53 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ReadFvecs reads vectors in the fvecs format: each vector is a
+// little-endian int32 dimension followed by that many float32 values
+func ReadFvecs(r io.Reader) ([][]float64, error) {
+	br := bufio.NewReader(r)
+	var vectors [][]float64
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("vector %d: failed to read dimension: %v", len(vectors), err)
+		}
+
+		dim := int32(binary.LittleEndian.Uint32(header[:]))
+		if dim <= 0 {
+			return nil, fmt.Errorf("vector %d: invalid dimension %d", len(vectors), dim)
+		}
+
+		v, err := readFloats(br, int(dim), 4)
+		if err != nil {
+			return nil, fmt.Errorf("vector %d: failed to read values: %w", len(vectors), err)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, checkDimensions(vectors)
+}
+
+// WriteFvecs writes vectors in the fvecs format
+func WriteFvecs(w io.Writer, vectors [][]float64) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 4)
+	for _, v := range vectors {
+		binary.LittleEndian.PutUint32(buf, uint32(len(v)))
+		bw.Write(buf)
+		for _, x := range v {
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(x)))
+			bw.Write(buf)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,99 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// npyMagic starts every .npy file
+const npyMagic = "\x93NUMPY"
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape':\s*\(\s*(\d+)\s*,\s*(\d+)\s*,?\s*\)`)
+)
+
+// ReadNpy reads a two-dimensional NumPy array of little-endian float32 or
+// float64 values in C order, one vector per row
+func ReadNpy(r io.Reader) ([][]float64, error) {
+	br := bufio.NewReader(r)
+
+	// Magic, version and header length
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, fmt.Errorf("failed to read npy header: %w", err)
+	}
+	if string(prefix[:6]) != npyMagic {
+		return nil, fmt.Errorf("not an npy file")
+	}
+
+	var headerLen int
+	switch prefix[6] {
+	case 1:
+		var n [2]byte
+		if _, err := io.ReadFull(br, n[:]); err != nil {
+			return nil, fmt.Errorf("failed to read npy header: %w", err)
+		}
+		headerLen = int(binary.LittleEndian.Uint16(n[:]))
+	case 2, 3:
+		var n [4]byte
+		if _, err := io.ReadFull(br, n[:]); err != nil {
+			return nil, fmt.Errorf("failed to read npy header: %w", err)
+		}
+		headerLen = int(binary.LittleEndian.Uint32(n[:]))
+	default:
+		return nil, fmt.Errorf("unsupported npy version %d", prefix[6])
+	}
+
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed to read npy header: %w", err)
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	shape := npyShape.FindSubmatch(header)
+	if descr == nil || shape == nil {
+		return nil, fmt.Errorf("npy header must describe a two-dimensional array: %s", header)
+	}
+	if m := npyFortran.FindSubmatch(header); m != nil && string(m[1]) == "True" {
+		return nil, fmt.Errorf("fortran-ordered npy arrays are not supported")
+	}
+
+	var size int
+	switch string(descr[1]) {
+	case "<f4":
+		size = 4
+	case "<f8":
+		size = 8
+	default:
+		return nil, fmt.Errorf("unsupported npy dtype %s, expected <f4 or <f8", descr[1])
+	}
+
+	rows, err := strconv.Atoi(string(shape[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid npy shape: %w", err)
+	}
+	cols, err := strconv.Atoi(string(shape[2]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid npy shape: %w", err)
+	}
+	if cols == 0 {
+		return nil, fmt.Errorf("npy array has no columns")
+	}
+
+	// The shape is untrusted, so rows are appended as they are read
+	vectors := make([][]float64, 0, min(rows, readChunk))
+	for i := 0; i < rows; i++ {
+		v, err := readFloats(br, cols, size)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: failed to read values: %w", i, err)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
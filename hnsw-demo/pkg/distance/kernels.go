@@ -0,0 +1,138 @@
+package distance
+
+import "math"
+
+// Kernels used by the distance functions. They assume equal lengths and are
+// replaced by assembly implementations at init when the CPU supports them
+var (
+	dotKernel         = dotGeneric
+	squaredL2Kernel   = squaredL2Generic
+	dotKernel32       = dotGeneric32
+	squaredL2Kernel32 = squaredL2Generic32
+
+	kernelImplementation = "generic"
+)
+
+// KernelImplementation returns the name of the kernels selected for this
+// CPU, either "avx2-fma" or "generic"
+func KernelImplementation() string {
+	return kernelImplementation
+}
+
+// dotGeneric computes a·b with four independent accumulators
+func dotGeneric(a, b []float64) float64 {
+	var s0, s1, s2, s3 float64
+	for len(a) >= 4 && len(b) >= 4 {
+		s0 += a[0] * b[0]
+		s1 += a[1] * b[1]
+		s2 += a[2] * b[2]
+		s3 += a[3] * b[3]
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// squaredL2Generic computes |a-b|² with four independent accumulators
+func squaredL2Generic(a, b []float64) float64 {
+	var s0, s1, s2, s3 float64
+	for len(a) >= 4 && len(b) >= 4 {
+		d0 := a[0] - b[0]
+		d1 := a[1] - b[1]
+		d2 := a[2] - b[2]
+		d3 := a[3] - b[3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// dotGeneric32 computes a·b with four independent accumulators
+func dotGeneric32(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	for len(a) >= 4 && len(b) >= 4 {
+		s0 += a[0] * b[0]
+		s1 += a[1] * b[1]
+		s2 += a[2] * b[2]
+		s3 += a[3] * b[3]
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// squaredL2Generic32 computes |a-b|² with four independent accumulators
+func squaredL2Generic32(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	for len(a) >= 4 && len(b) >= 4 {
+		d0 := a[0] - b[0]
+		d1 := a[1] - b[1]
+		d2 := a[2] - b[2]
+		d3 := a[3] - b[3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// SquaredEuclideanFloat32 calculates the squared Euclidean distance between
+// float32 vectors
+func SquaredEuclideanFloat32(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return float32(math.Inf(1))
+	}
+	return squaredL2Kernel32(a, b)
+}
+
+// EuclideanDistanceFloat32 calculates Euclidean distance between float32 vectors
+func EuclideanDistanceFloat32(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return float32(math.Inf(1))
+	}
+	return float32(math.Sqrt(float64(squaredL2Kernel32(a, b))))
+}
+
+// InnerProductFloat32 calculates the inner product a·b of float32 vectors
+func InnerProductFloat32(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return float32(math.Inf(-1))
+	}
+	return dotKernel32(a, b)
+}
+
+// CosineDistanceFloat32 calculates Cosine distance between float32 vectors
+func CosineDistanceFloat32(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return float32(math.Inf(1))
+	}
+
+	normA := dotKernel32(a, a)
+	normB := dotKernel32(b, b)
+	if normA == 0 || normB == 0 {
+		return float32(math.Inf(1))
+	}
+
+	similarity := float64(dotKernel32(a, b)) / (math.Sqrt(float64(normA)) * math.Sqrt(float64(normB)))
+	if similarity > 1 {
+		similarity = 1
+	}
+	return float32(1 - similarity)
+}
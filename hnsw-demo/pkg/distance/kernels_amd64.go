@@ -0,0 +1,64 @@
+//go:build amd64 && !purego
+
+package distance
+
+func init() {
+	if hasAVX2FMA() {
+		dotKernel = dotAVX2
+		squaredL2Kernel = squaredL2AVX2
+		dotKernel32 = dotAVX2F32
+		squaredL2Kernel32 = squaredL2AVX2F32
+		kernelImplementation = "avx2-fma"
+	}
+}
+
+// hasAVX2FMA reports whether the CPU and OS support AVX2 and FMA, following
+// the checks of golang.org/x/sys/cpu
+func hasAVX2FMA() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+
+	_, _, ecx1, _ := cpuid(1, 0)
+	hasFMA := ecx1&(1<<12) != 0
+	hasOSXSAVE := ecx1&(1<<27) != 0
+	hasAVX := ecx1&(1<<28) != 0
+	if !hasFMA || !hasOSXSAVE || !hasAVX {
+		return false
+	}
+
+	// The OS must save the XMM and YMM registers on context switches
+	if eax, _ := xgetbv(); eax&0x6 != 0x6 {
+		return false
+	}
+
+	_, ebx7, _, _ := cpuid(7, 0)
+	return ebx7&(1<<5) != 0
+}
+
+// cpuid executes the CPUID instruction for the given leaf and subleaf
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// xgetbv reads the XCR0 extended control register
+func xgetbv() (eax, edx uint32)
+
+// dotAVX2 computes a·b for len(a) elements using AVX2 and FMA
+//
+//go:noescape
+func dotAVX2(a, b []float64) float64
+
+// squaredL2AVX2 computes |a-b|² for len(a) elements using AVX2 and FMA
+//
+//go:noescape
+func squaredL2AVX2(a, b []float64) float64
+
+// dotAVX2F32 computes a·b for len(a) elements using AVX2 and FMA
+//
+//go:noescape
+func dotAVX2F32(a, b []float32) float32
+
+// squaredL2AVX2F32 computes |a-b|² for len(a) elements using AVX2 and FMA
+//
+//go:noescape
+func squaredL2AVX2F32(a, b []float32) float32
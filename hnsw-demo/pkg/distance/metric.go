@@ -3,6 +3,7 @@ package distance
 import (
 	"fmt"
 	"math"
+	"strconv"
 )
 
 // DistanceFunction defines interface for distance calculation
@@ -14,22 +15,28 @@ const (
 	Manhattan  = "manhattan"
 	Cosine     = "cosine"
 	DotProduct = "dot"
+	Chebyshev  = "chebyshev"
+	Hamming    = "hamming"
+	Jaccard    = "jaccard"
+	Tanimoto   = "tanimoto"
+	Canberra   = "canberra"
+
+	// NormalizedCosine is cosine distance computed as 1 - a·b on vectors
+	// the index normalizes once at insert
+	NormalizedCosine = "normalized-cosine"
+
+	// Minkowski is parameterized by p and selected as "minkowski:<p>",
+	// see MinkowskiName
+	Minkowski = "minkowski"
 )
 
-// GetDistanceFunction returns the corresponding distance function
+// GetDistanceFunction returns the distance function registered under metric
 func GetDistanceFunction(metric string) (DistanceFunction, error) {
-	switch metric {
-	case Euclidean:
-		return EuclideanDistance, nil
-	case Manhattan:
-		return ManhattanDistance, nil
-	case Cosine:
-		return CosineDistance, nil
-	case DotProduct:
-		return DotProductDistance, nil
-	default:
-		return nil, fmt.Errorf("unsupported distance metric: %s", metric)
+	m, err := Lookup(metric)
+	if err != nil {
+		return nil, err
 	}
+	return m.Func, nil
 }
 
 // EuclideanDistance calculates Euclidean distance between vectors
@@ -38,12 +45,17 @@ func EuclideanDistance(a, b []float64) float64 {
 		return math.Inf(1)
 	}
 
-	sum := 0.0
-	for i := range a {
-		diff := a[i] - b[i]
-		sum += diff * diff
+	return math.Sqrt(squaredL2Kernel(a, b))
+}
+
+// SquaredEuclideanDistance calculates the squared Euclidean distance. It
+// orders vectors like EuclideanDistance without taking a square root
+func SquaredEuclideanDistance(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
 	}
-	return math.Sqrt(sum)
+
+	return squaredL2Kernel(a, b)
 }
 
 // ManhattanDistance calculates Manhattan distance between vectors
@@ -65,18 +77,13 @@ func CosineDistance(a, b []float64) float64 {
 		return math.Inf(1)
 	}
 
-	var dotProduct, normA, normB float64
-	for i := range a {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
-	}
-
+	normA := dotKernel(a, a)
+	normB := dotKernel(b, b)
 	if normA == 0 || normB == 0 {
 		return math.Inf(1)
 	}
 
-	similarity := dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
+	similarity := dotKernel(a, b) / (math.Sqrt(normA) * math.Sqrt(normB))
 	if similarity > 1 {
 		similarity = 1
 	}
@@ -84,17 +91,126 @@ func CosineDistance(a, b []float64) float64 {
 	return 1 - similarity
 }
 
+// NormalizedCosineDistance calculates cosine distance for unit-length
+// vectors as 1 - a·b. Like CosineDistance it is +Inf if either vector is
+// zero, which NormalizeVector leaves unchanged
+func NormalizedCosineDistance(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+
+	dotProduct := dotKernel(a, b)
+	if dotProduct == 0 && (isZero(a) || isZero(b)) {
+		return math.Inf(1)
+	}
+	if dotProduct > 1 {
+		dotProduct = 1
+	}
+	return 1 - dotProduct
+}
+
 // DotProductDistance calculates negative dot product distance
 func DotProductDistance(a, b []float64) float64 {
 	if len(a) != len(b) {
 		return math.Inf(1)
 	}
 
-	var dotProduct float64
+	return -dotKernel(a, b)
+}
+
+// ChebyshevDistance calculates the maximum absolute difference between vectors
+func ChebyshevDistance(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+
+	maxDiff := 0.0
+	for i := range a {
+		if diff := math.Abs(a[i] - b[i]); diff > maxDiff {
+			maxDiff = diff
+		}
+	}
+	return maxDiff
+}
+
+// MinkowskiDistance returns the Minkowski distance of order p. It is a true
+// metric for p >= 1; p = 1 and p = 2 match Manhattan and Euclidean
+func MinkowskiDistance(p float64) DistanceFunction {
+	return func(a, b []float64) float64 {
+		if len(a) != len(b) {
+			return math.Inf(1)
+		}
+
+		sum := 0.0
+		for i := range a {
+			sum += math.Pow(math.Abs(a[i]-b[i]), p)
+		}
+		return math.Pow(sum, 1/p)
+	}
+}
+
+// MinkowskiName returns the metric name that selects MinkowskiDistance(p)
+func MinkowskiName(p float64) string {
+	return fmt.Sprintf("%s:%s", Minkowski, strconv.FormatFloat(p, 'g', -1, 64))
+}
+
+// HammingDistance counts the positions at which vectors differ. It is meant
+// for binary vectors holding 0s and 1s
+func HammingDistance(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+
+	count := 0
 	for i := range a {
-		dotProduct += a[i] * b[i]
+		if a[i] != b[i] {
+			count++
+		}
 	}
-	return -dotProduct
+	return float64(count)
+}
+
+// JaccardDistance calculates 1 - |A ∩ B| / |A ∪ B| for bitsets, where every
+// non-zero element is a set bit. On binary vectors it equals the Tanimoto
+// distance. Two empty sets have distance 0
+func JaccardDistance(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+
+	var intersection, union int
+	for i := range a {
+		inA, inB := a[i] != 0, b[i] != 0
+		if inA && inB {
+			intersection++
+		}
+		if inA || inB {
+			union++
+		}
+	}
+
+	if union == 0 {
+		return 0
+	}
+	return 1 - float64(intersection)/float64(union)
+}
+
+// CanberraDistance calculates the sum of |a-b| / (|a|+|b|), skipping
+// positions where both elements are zero
+func CanberraDistance(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+
+	sum := 0.0
+	for i := range a {
+		denom := math.Abs(a[i]) + math.Abs(b[i])
+		if denom == 0 {
+			continue
+		}
+		sum += math.Abs(a[i]-b[i]) / denom
+	}
+	return sum
 }
 
 // ValidateVectors checks if vectors have same dimension
@@ -105,13 +221,19 @@ func ValidateVectors(a, b []float64) error {
 	return nil
 }
 
+// isZero reports whether every component of v is zero
+func isZero(v []float64) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // NormalizeVector normalizes vector to unit length
 func NormalizeVector(v []float64) []float64 {
-	norm := 0.0
-	for _, val := range v {
-		norm += val * val
-	}
-	norm = math.Sqrt(norm)
+	norm := math.Sqrt(dotKernel(v, v))
 
 	if norm == 0 {
 		return v
@@ -0,0 +1,148 @@
+package distance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Properties describes the mathematical properties of a distance function
+type Properties struct {
+	// Whether the function is a true metric: non-negative, symmetric and
+	// satisfying the triangle inequality
+	IsMetric bool
+
+	// Whether vectors must be normalized to unit length before use
+	RequiresNormalization bool
+}
+
+// Metric is a distance function registered under a name
+type Metric struct {
+	Name       string
+	Func       DistanceFunction
+	Properties Properties
+
+	// Optional cheaper function that orders vectors exactly like Func, used
+	// inside the index. Finalize maps its values back to those of Func
+	Internal DistanceFunction
+	Finalize func(float64) float64
+}
+
+// InternalFunc returns the function an index should compare vectors with
+func (m Metric) InternalFunc() DistanceFunction {
+	if m.Internal != nil {
+		return m.Internal
+	}
+	return m.Func
+}
+
+// FinalizeDistance converts a value of InternalFunc into a value of Func
+func (m Metric) FinalizeDistance(d float64) float64 {
+	if m.Finalize != nil {
+		return m.Finalize(d)
+	}
+	return d
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Metric)
+)
+
+func init() {
+	builtins := []Metric{
+		{
+			Name:       Euclidean,
+			Func:       EuclideanDistance,
+			Properties: Properties{IsMetric: true},
+			Internal:   SquaredEuclideanDistance,
+			Finalize:   math.Sqrt,
+		},
+		{Name: Manhattan, Func: ManhattanDistance, Properties: Properties{IsMetric: true}},
+		{Name: Cosine, Func: CosineDistance},
+		{Name: NormalizedCosine, Func: NormalizedCosineDistance, Properties: Properties{RequiresNormalization: true}},
+		{Name: DotProduct, Func: DotProductDistance},
+		{Name: Chebyshev, Func: ChebyshevDistance, Properties: Properties{IsMetric: true}},
+		{Name: Hamming, Func: HammingDistance, Properties: Properties{IsMetric: true}},
+		{Name: Jaccard, Func: JaccardDistance, Properties: Properties{IsMetric: true}},
+		{Name: Tanimoto, Func: JaccardDistance, Properties: Properties{IsMetric: true}},
+		{Name: Canberra, Func: CanberraDistance, Properties: Properties{IsMetric: true}},
+	}
+	for _, m := range builtins {
+		registry[m.Name] = m
+	}
+}
+
+// Register adds a distance function under name so that it can be selected
+// by name when creating or loading an index
+func Register(name string, fn DistanceFunction, props Properties) error {
+	return RegisterMetric(Metric{Name: name, Func: fn, Properties: props})
+}
+
+// RegisterMetric is Register for a metric that also provides an internal
+// function and the conversion back from it
+func RegisterMetric(m Metric) error {
+	if m.Name == "" {
+		return fmt.Errorf("metric name must not be empty")
+	}
+	if m.Func == nil {
+		return fmt.Errorf("distance function for metric %s must not be nil", m.Name)
+	}
+	if (m.Internal == nil) != (m.Finalize == nil) {
+		return fmt.Errorf("metric %s must set both or neither of Internal and Finalize", m.Name)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[m.Name]; exists {
+		return fmt.Errorf("metric %s is already registered", m.Name)
+	}
+	registry[m.Name] = m
+	return nil
+}
+
+// Lookup returns the metric registered under name. Parameterized metrics
+// such as "minkowski:3" are built on demand
+func Lookup(name string) (Metric, error) {
+	registryMutex.RLock()
+	m, exists := registry[name]
+	registryMutex.RUnlock()
+
+	if exists {
+		return m, nil
+	}
+	if family, param, found := strings.Cut(name, ":"); found && family == Minkowski {
+		return lookupMinkowski(name, param)
+	}
+	return Metric{}, fmt.Errorf("unsupported distance metric: %s", name)
+}
+
+// lookupMinkowski builds the Minkowski metric for the order in param
+func lookupMinkowski(name string, param string) (Metric, error) {
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil || math.IsNaN(p) || p <= 0 || math.IsInf(p, 0) {
+		return Metric{}, fmt.Errorf("invalid Minkowski order in metric %s", name)
+	}
+	return Metric{
+		Name:       name,
+		Func:       MinkowskiDistance(p),
+		Properties: Properties{IsMetric: p >= 1},
+	}, nil
+}
+
+// RegisteredMetrics returns the names of all registered metrics, sorted
+func RegisteredMetrics() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -27,9 +27,13 @@ func (pq PriorityQueue) Len() int {
 }
 
 // Less determines the priority of items
-// For min-heap (nearest neighbors), we want smaller distances to have higher priority
+// For min-heap (nearest neighbors), we want smaller distances to have higher priority.
+// Ties are broken by node ID so that results are deterministic
 func (pq PriorityQueue) Less(i, j int) bool {
-	return pq[i].Distance < pq[j].Distance
+	if pq[i].Distance != pq[j].Distance {
+		return pq[i].Distance < pq[j].Distance
+	}
+	return pq[i].NodeID < pq[j].NodeID
 }
 
 // Swap swaps two items in the queue
@@ -110,3 +114,78 @@ func (pq *PriorityQueue) Update(nodeID int, distance float64) bool {
 	}
 	return false
 }
+
+// MaxPriorityQueue is a max-heap of Items, used to track the furthest
+// element of a bounded result set
+type MaxPriorityQueue []*Item
+
+// NewMaxPriorityQueue creates a new max priority queue
+func NewMaxPriorityQueue() *MaxPriorityQueue {
+	pq := make(MaxPriorityQueue, 0)
+	heap.Init(&pq)
+	return &pq
+}
+
+// Len returns the length of the queue
+func (pq MaxPriorityQueue) Len() int {
+	return len(pq)
+}
+
+// Less puts larger distances first
+func (pq MaxPriorityQueue) Less(i, j int) bool {
+	if pq[i].Distance != pq[j].Distance {
+		return pq[i].Distance > pq[j].Distance
+	}
+	return pq[i].NodeID > pq[j].NodeID
+}
+
+// Swap swaps two items in the queue
+func (pq MaxPriorityQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].Index = i
+	pq[j].Index = j
+}
+
+// Push adds an item to the queue
+func (pq *MaxPriorityQueue) Push(x interface{}) {
+	n := len(*pq)
+	item := x.(*Item)
+	item.Index = n
+	*pq = append(*pq, item)
+}
+
+// Pop removes and returns the last item of the underlying slice
+func (pq *MaxPriorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil  // avoid memory leak
+	item.Index = -1 // for safety
+	*pq = old[0 : n-1]
+	return item
+}
+
+// PushItem adds a new item to the queue
+func (pq *MaxPriorityQueue) PushItem(nodeID int, distance float64) {
+	heap.Push(pq, &Item{
+		NodeID:   nodeID,
+		Distance: distance,
+	})
+}
+
+// PopItem removes and returns the furthest node ID and its distance
+func (pq *MaxPriorityQueue) PopItem() (int, float64) {
+	if pq.Len() == 0 {
+		return -1, -1
+	}
+	item := heap.Pop(pq).(*Item)
+	return item.NodeID, item.Distance
+}
+
+// Top returns the furthest item without removing it
+func (pq MaxPriorityQueue) Top() (*Item, bool) {
+	if pq.Len() == 0 {
+		return nil, false
+	}
+	return pq[0], true
+}
@@ -3,6 +3,9 @@ package node
 import (
 	"fmt"
 	"sync"
+	"unsafe"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
 )
 
 // Node represents a node in the HNSW graph
@@ -12,10 +15,19 @@ type Node struct {
 	Vector []float64
 	Level  int
 
+	// Vector as inserted, kept when Vector holds a normalized copy
+	Original []float64
+
+	// Sparse vector, set instead of Vector in sparse indexes
+	Sparse sparse.Vector
+
 	// Neighbors at each level
 	// map[level][]neighborID
 	Neighbors map[int][]int
 
+	// Optional payload attached to the vector
+	Attributes map[string]string
+
 	// Concurrency control
 	mutex sync.RWMutex
 
@@ -34,6 +46,17 @@ func NewNode(id int, vector []float64, level int) *Node {
 	}
 }
 
+// NewSparseNode creates a new node holding a sparse vector
+func NewSparseNode(id int, vector sparse.Vector, level int) *Node {
+	return &Node{
+		ID:        id,
+		Sparse:    vector,
+		Level:     level,
+		Neighbors: make(map[int][]int),
+		deleted:   false,
+	}
+}
+
 // AddNeighbor adds a neighbor at specified level
 func (n *Node) AddNeighbor(level int, neighborID int) error {
 	n.mutex.Lock()
@@ -108,6 +131,19 @@ func (n *Node) GetNeighbors(level int) ([]int, error) {
 	return result, nil
 }
 
+// GetAllNeighbors returns a copy of the neighbor lists of every level,
+// including levels above the node's own level
+func (n *Node) GetAllNeighbors() map[int][]int {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	result := make(map[int][]int, len(n.Neighbors))
+	for level, neighbors := range n.Neighbors {
+		result[level] = append([]int(nil), neighbors...)
+	}
+	return result
+}
+
 // SetNeighbors sets all neighbors at specified level
 func (n *Node) SetNeighbors(level int, neighbors []int) error {
 	n.mutex.Lock()
@@ -151,6 +187,75 @@ func (n *Node) GetVector() []float64 {
 	return result
 }
 
+// GetInsertedVector returns a copy of the vector as it was inserted: the
+// original if Vector holds a normalized copy, Vector otherwise
+func (n *Node) GetInsertedVector() []float64 {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	if n.Original != nil {
+		return append([]float64(nil), n.Original...)
+	}
+	return append([]float64(nil), n.Vector...)
+}
+
+// GetSparse returns a copy of the node's sparse vector
+func (n *Node) GetSparse() sparse.Vector {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.Sparse.Clone()
+}
+
+// SetAttributes replaces the node's attributes with a copy of attrs
+func (n *Node) SetAttributes(attrs map[string]string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.Attributes = copyAttributes(attrs)
+}
+
+// GetAttributes returns a copy of the node's attributes
+func (n *Node) GetAttributes() map[string]string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return copyAttributes(n.Attributes)
+}
+
+// copyAttributes returns a copy of attrs, or nil if attrs is empty
+func copyAttributes(attrs map[string]string) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		result[k] = v
+	}
+	return result
+}
+
+// MemoryUsage estimates the bytes held by the node: the struct itself, its
+// vectors, neighbor lists and attributes. Map overhead is approximated
+func (n *Node) MemoryUsage() int64 {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	const (
+		word        = int64(unsafe.Sizeof(0))
+		float       = int64(unsafe.Sizeof(float64(0)))
+		sliceHeader = int64(unsafe.Sizeof([]int(nil)))
+		str         = int64(unsafe.Sizeof(""))
+	)
+
+	bytes := int64(unsafe.Sizeof(*n))
+	bytes += int64(cap(n.Vector)+cap(n.Original)) * float
+	bytes += int64(cap(n.Sparse.Indices))*word + int64(cap(n.Sparse.Values))*float
+	for _, neighbors := range n.Neighbors {
+		bytes += word + sliceHeader + int64(cap(neighbors))*word
+	}
+	for k, v := range n.Attributes {
+		bytes += 2*str + int64(len(k)+len(v))
+	}
+	return bytes
+}
+
 // GetLevel returns the node's level
 func (n *Node) GetLevel() int {
 	n.mutex.RLock()
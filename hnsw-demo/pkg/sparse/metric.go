@@ -0,0 +1,44 @@
+package sparse
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+)
+
+// DistanceFunction defines interface for distance calculation between
+// sparse vectors
+type DistanceFunction func(a, b Vector) float64
+
+// GetDistanceFunction returns the sparse distance function for metric.
+// Sparse vectors support the distance.DotProduct and distance.Cosine metrics
+func GetDistanceFunction(metric string) (DistanceFunction, error) {
+	switch metric {
+	case distance.DotProduct:
+		return DotProductDistance, nil
+	case distance.Cosine:
+		return CosineDistance, nil
+	default:
+		return nil, fmt.Errorf("unsupported sparse distance metric: %s", metric)
+	}
+}
+
+// DotProductDistance calculates negative inner product distance
+func DotProductDistance(a, b Vector) float64 {
+	return -Dot(a, b)
+}
+
+// CosineDistance calculates Cosine distance between sparse vectors
+func CosineDistance(a, b Vector) float64 {
+	normA, normB := a.Norm(), b.Norm()
+	if normA == 0 || normB == 0 {
+		return math.Inf(1)
+	}
+
+	similarity := Dot(a, b) / (normA * normB)
+	if similarity > 1 {
+		similarity = 1
+	}
+	return 1 - similarity
+}
@@ -0,0 +1,116 @@
+package sparse
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Vector is a sparse vector stored as strictly increasing indices and
+// their values
+type Vector struct {
+	Indices []int
+	Values  []float64
+}
+
+// NewVector creates a sparse vector from parallel index and value slices.
+// The input is copied and sorted; duplicate or negative indices are rejected
+func NewVector(indices []int, values []float64) (Vector, error) {
+	if len(indices) != len(values) {
+		return Vector{}, fmt.Errorf("indices and values length mismatch: %d != %d", len(indices), len(values))
+	}
+
+	order := make([]int, len(indices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return indices[order[i]] < indices[order[j]] })
+
+	v := Vector{
+		Indices: make([]int, len(indices)),
+		Values:  make([]float64, len(values)),
+	}
+	for i, j := range order {
+		v.Indices[i] = indices[j]
+		v.Values[i] = values[j]
+	}
+
+	if err := v.Validate(); err != nil {
+		return Vector{}, err
+	}
+	return v, nil
+}
+
+// FromMap creates a sparse vector from an index to value map, skipping zeros
+func FromMap(m map[int]float64) (Vector, error) {
+	indices := make([]int, 0, len(m))
+	values := make([]float64, 0, len(m))
+	for index, value := range m {
+		if value != 0 {
+			indices = append(indices, index)
+			values = append(values, value)
+		}
+	}
+	return NewVector(indices, values)
+}
+
+// Validate checks that indices are non-negative, strictly increasing and
+// match the values in length
+func (v Vector) Validate() error {
+	if len(v.Indices) != len(v.Values) {
+		return fmt.Errorf("indices and values length mismatch: %d != %d", len(v.Indices), len(v.Values))
+	}
+	for i, index := range v.Indices {
+		if index < 0 {
+			return fmt.Errorf("negative index %d", index)
+		}
+		if i > 0 && index <= v.Indices[i-1] {
+			return fmt.Errorf("indices must be strictly increasing, got %d after %d", index, v.Indices[i-1])
+		}
+	}
+	return nil
+}
+
+// Len returns the number of stored (non-zero) entries
+func (v Vector) Len() int {
+	return len(v.Indices)
+}
+
+// Clone returns a deep copy of the vector
+func (v Vector) Clone() Vector {
+	result := Vector{
+		Indices: make([]int, len(v.Indices)),
+		Values:  make([]float64, len(v.Values)),
+	}
+	copy(result.Indices, v.Indices)
+	copy(result.Values, v.Values)
+	return result
+}
+
+// Dot returns the inner product of two sparse vectors
+func Dot(a, b Vector) float64 {
+	var sum float64
+	i, j := 0, 0
+	for i < len(a.Indices) && j < len(b.Indices) {
+		switch {
+		case a.Indices[i] == b.Indices[j]:
+			sum += a.Values[i] * b.Values[j]
+			i++
+			j++
+		case a.Indices[i] < b.Indices[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return sum
+}
+
+// Norm returns the Euclidean norm of the vector
+func (v Vector) Norm() float64 {
+	var sum float64
+	for _, value := range v.Values {
+		sum += value * value
+	}
+	return math.Sqrt(sum)
+}
@@ -1,13 +1,19 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/gob"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/node"
 )
 
@@ -18,7 +24,11 @@ type IndexMetadata struct {
 	NodesCount  int           // Number of nodes in the index
 	MaxLevel    int           // Maximum level in the index
 	Config      config.Config // Index configuration
+	Metric      string        // Registered name of the distance metric
+	Sparse      bool          // Whether nodes hold sparse vectors
 	Description string        // Optional description
+	Seed        int64         // Seed of the level generator
+	DefaultEf   int           // ef of searches that do not set one, 0 for 2*K
 }
 
 // SaveData represents the complete state of the index
@@ -28,73 +38,205 @@ type SaveData struct {
 	EntryPoint int
 }
 
+// Format is an on-disk encoding of SaveData
+type Format string
+
+const (
+	// FormatGob is the compact binary default
+	FormatGob Format = "gob"
+
+	// FormatJSON is human-readable, used for files ending in .json
+	FormatJSON Format = "json"
+)
+
+// FormatFromFilename returns the format implied by the extension of filename
+func FormatFromFilename(filename string) Format {
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		return FormatJSON
+	}
+	return FormatGob
+}
+
+// ParseFormat parses a format name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatGob:
+		return FormatGob, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown storage format %q", name)
+	}
+}
+
 // SaveIndex saves the index state to a file
 func SaveIndex(filename string, nodes map[int]*node.Node, entryPoint int,
 	maxLevel int, cfg config.Config, description string) error {
 
+	return Save(filename, &SaveData{
+		Metadata: IndexMetadata{
+			MaxLevel:    maxLevel,
+			Config:      cfg,
+			Description: description,
+		},
+		Nodes:      nodes,
+		EntryPoint: entryPoint,
+	})
+}
+
+// Save writes data to a file in the format implied by its extension. The
+// format version and node count of the metadata are filled in
+// automatically, and so is the creation time unless it is already set
+func Save(filename string, data *SaveData) error {
+	return SaveAs(filename, data, FormatFromFilename(filename))
+}
+
+// SaveAs is Save with an explicit format. The data is written to a
+// temporary file in the same directory and renamed over filename, so a
+// failed save leaves any previous file intact
+func SaveAs(filename string, data *SaveData, format Format) error {
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(filename)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %v", err)
+		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create file
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %v", err)
+	// Prepare metadata
+	data.Metadata.Version = "1.0"
+	if data.Metadata.CreatedAt.IsZero() {
+		data.Metadata.CreatedAt = time.Now()
 	}
-	defer file.Close()
+	data.Metadata.NodesCount = len(data.Nodes)
 
-	// Prepare metadata
-	metadata := IndexMetadata{
-		Version:     "1.0",
-		CreatedAt:   time.Now(),
-		NodesCount:  len(nodes),
-		MaxLevel:    maxLevel,
-		Config:      cfg,
-		Description: description,
-	}
-
-	// Prepare save data
-	data := SaveData{
-		Metadata:   metadata,
-		Nodes:      nodes,
-		EntryPoint: entryPoint,
+	file, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	tmp := file.Name()
+	if err := writeData(file, data, format); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace file: %w", err)
 	}
+	return nil
+}
 
-	// Create encoder and encode data
-	encoder := gob.NewEncoder(file)
+// writeData encodes data to file in format. The file gets the usual
+// permissions of a saved index rather than the private ones of a temp file
+func writeData(file *os.File, data *SaveData, format Format) error {
+	var encoder interface{ Encode(interface{}) error }
+	switch format {
+	case FormatGob:
+		encoder = gob.NewEncoder(file)
+	case FormatJSON:
+		encoder = json.NewEncoder(file)
+	default:
+		return fmt.Errorf("unknown storage format %q", format)
+	}
+	if err := file.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
 	if err := encoder.Encode(data); err != nil {
-		return fmt.Errorf("failed to encode data: %v", err)
+		return fmt.Errorf("failed to encode data: %w", err)
 	}
-
 	return nil
 }
 
+// detectPeekBytes is how much of a file detectFormat looks at
+const detectPeekBytes = 512
+
+// detectFormat returns the format of the data r starts with: JSON if it
+// opens an object, gob otherwise. A gob stream starts with the length of
+// a type definition followed by its negative type id, which never
+// encodes as the quote of a JSON key
+func detectFormat(r *bufio.Reader) (Format, error) {
+	prefix, err := r.Peek(detectPeekBytes)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+	prefix = bytes.TrimLeft(prefix, jsonSpace)
+	if len(prefix) > 0 && prefix[0] == '{' {
+		rest := bytes.TrimLeft(prefix[1:], jsonSpace)
+		if len(rest) > 0 && (rest[0] == '"' || rest[0] == '}') {
+			return FormatJSON, nil
+		}
+	}
+	return FormatGob, nil
+}
+
+// jsonSpace holds the whitespace JSON allows between tokens
+const jsonSpace = " \t\r\n"
+
+// DetectFormat returns the format the index in filename is stored in,
+// judged from its contents rather than its extension
+func DetectFormat(filename string) (Format, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return detectFormat(bufio.NewReader(file))
+}
+
+// decode reads SaveData in the format detected from the contents of r
+func decode(r io.Reader) (*SaveData, error) {
+	br := bufio.NewReader(r)
+	format, err := detectFormat(br)
+	if err != nil {
+		return nil, err
+	}
+	var decoder interface{ Decode(interface{}) error }
+	if format == FormatJSON {
+		decoder = json.NewDecoder(br)
+	} else {
+		decoder = gob.NewDecoder(br)
+	}
+
+	var data SaveData
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+	return &data, nil
+}
+
 // LoadIndex loads the index state from a file
 func LoadIndex(filename string) (map[int]*node.Node, int, config.Config, error) {
+	data, err := Load(filename)
+	if err != nil {
+		return nil, 0, config.Config{}, err
+	}
+	return data.Nodes, data.EntryPoint, data.Metadata.Config, nil
+}
+
+// Load reads and validates the complete index state from a file
+func Load(filename string) (*SaveData, error) {
 	// Open file
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, 0, config.Config{}, fmt.Errorf("failed to open file: %v", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	// Create decoder
-	decoder := gob.NewDecoder(file)
-
 	// Decode data
-	var data SaveData
-	if err := decoder.Decode(&data); err != nil {
-		return nil, 0, config.Config{}, fmt.Errorf("failed to decode data: %v", err)
+	data, err := decode(file)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate loaded data
-	if err := validateLoadedData(&data); err != nil {
-		return nil, 0, config.Config{}, fmt.Errorf("invalid data: %v", err)
+	if err := validateLoadedData(data); err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
 	}
 
-	return data.Nodes, data.EntryPoint, data.Metadata.Config, nil
+	return data, nil
 }
 
 // CreateBackup creates a backup of the index file
@@ -106,18 +248,18 @@ func CreateBackup(sourceFile string) error {
 	// Copy source file to backup
 	source, err := os.Open(sourceFile)
 	if err != nil {
-		return fmt.Errorf("failed to open source file: %v", err)
+		return fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer source.Close()
 
 	destination, err := os.Create(backupFile)
 	if err != nil {
-		return fmt.Errorf("failed to create backup file: %v", err)
+		return fmt.Errorf("failed to create backup file: %w", err)
 	}
 	defer destination.Close()
 
 	if _, err := destination.ReadFrom(source); err != nil {
-		return fmt.Errorf("failed to copy data: %v", err)
+		return fmt.Errorf("failed to copy data: %w", err)
 	}
 
 	return nil
@@ -138,7 +280,7 @@ func validateLoadedData(data *SaveData) error {
 
 	// Validate config
 	if err := data.Metadata.Config.Validate(); err != nil {
-		return fmt.Errorf("invalid config: %v", err)
+		return fmt.Errorf("invalid config: %w", err)
 	}
 
 	// Validate entry point
@@ -146,6 +288,13 @@ func validateLoadedData(data *SaveData) error {
 		return fmt.Errorf("invalid entry point: %d", data.EntryPoint)
 	}
 
+	// Validate metric, which must be registered before loading
+	if data.Metadata.Metric != "" {
+		if _, err := distance.Lookup(data.Metadata.Metric); err != nil {
+			return fmt.Errorf("unknown metric: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -153,14 +302,13 @@ func validateLoadedData(data *SaveData) error {
 func GetIndexInfo(filename string) (*IndexMetadata, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %v", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	var data SaveData
-	if err := decoder.Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to decode data: %v", err)
+	data, err := decode(file)
+	if err != nil {
+		return nil, err
 	}
 
 	return &data.Metadata, nil
@@ -0,0 +1,227 @@
+package algorithm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/node"
+)
+
+// BulkOrder is the order in which BulkLoad links the elements of a level
+type BulkOrder int
+
+const (
+	// OrderInput links elements in the order they were given
+	OrderInput BulkOrder = iota
+
+	// OrderShuffle links elements in a random order drawn from the index's
+	// level generator, which avoids degenerate graphs on sorted input
+	OrderShuffle
+
+	// OrderCluster groups elements by their nearest of a sample of pivots,
+	// so that neighbors are linked close together in time
+	OrderCluster
+)
+
+// String returns the name of the order
+func (o BulkOrder) String() string {
+	switch o {
+	case OrderInput:
+		return "input"
+	case OrderShuffle:
+		return "shuffle"
+	case OrderCluster:
+		return "cluster"
+	default:
+		return fmt.Sprintf("BulkOrder(%d)", int(o))
+	}
+}
+
+// ParseBulkOrder returns the order with the given name
+func ParseBulkOrder(name string) (BulkOrder, error) {
+	for _, o := range []BulkOrder{OrderInput, OrderShuffle, OrderCluster} {
+		if o.String() == name {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown bulk order %q", name)
+}
+
+// edgeLockStripes is the number of locks serializing edge updates during
+// a bulk load
+const edgeLockStripes = 1024
+
+// maxClusterPivots bounds the pivots OrderCluster samples
+const maxClusterPivots = 256
+
+// BulkOptions controls BulkLoad
+type BulkOptions struct {
+	// Goroutines linking elements in parallel, 0 for GOMAXPROCS. With one
+	// worker and a fixed seed the graph is deterministic
+	Workers int
+
+	// Order in which the elements of each level are linked
+	Order BulkOrder
+}
+
+// BulkLoad builds the graph of an empty index from records faster than
+// inserting them one by one. Levels are assigned up front, then elements
+// are linked level group by level group from the top down, so every
+// element finds the layers above its own complete, and each group is
+// linked by parallel workers. The result is an ordinary index that
+// accepts Insert and Delete afterwards. BulkLoad must not run
+// concurrently with other writes; if ctx is cancelled the index is left
+// empty
+func (h *HNSW) BulkLoad(ctx context.Context, records []Record, opts BulkOptions) error {
+	if h.IsSparse() {
+		return ErrSparseIndex
+	}
+	if opts.Workers < 0 {
+		return fmt.Errorf("workers must not be negative, got %d", opts.Workers)
+	}
+	if opts.Order < OrderInput || opts.Order > OrderCluster {
+		return fmt.Errorf("unknown bulk order %v", opts.Order)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	dimension := len(records[0].Vector)
+	seen := make(map[int]bool, len(records))
+	for _, record := range records {
+		if seen[record.ID] {
+			return fmt.Errorf("%w: %d", ErrNodeExists, record.ID)
+		}
+		seen[record.ID] = true
+		if len(record.Vector) != dimension {
+			return fmt.Errorf("%w: expected %d, got %d for id %d", ErrDimensionMismatch, dimension, len(record.Vector), record.ID)
+		}
+	}
+
+	h.nodesMutex.Lock()
+	if len(h.nodes) > 0 {
+		h.nodesMutex.Unlock()
+		return fmt.Errorf("bulk load needs an empty index, it holds %d elements", len(h.nodes))
+	}
+
+	// Create every node, with levels drawn in input order
+	nodes := make([]*node.Node, len(records))
+	for i, record := range records {
+		nodes[i] = h.newDenseNode(record.ID, record.Vector, h.generateLevel())
+		nodes[i].SetAttributes(record.Attributes)
+		h.nodes[record.ID] = nodes[i]
+	}
+	h.dimension = dimension
+	h.nodesMutex.Unlock()
+
+	order := h.bulkOrder(nodes, opts)
+	sort.SliceStable(order, func(i, j int) bool {
+		return nodes[order[i]].Level > nodes[order[j]].Level
+	})
+
+	top := nodes[order[0]]
+	h.mutex.Lock()
+	h.entryPoint, h.maxLevel = top.ID, top.Level
+	h.mutex.Unlock()
+
+	h.edgeLocks = make([]sync.Mutex, edgeLockStripes)
+	defer func() { h.edgeLocks = nil }()
+
+	workers := opts.Workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Link each level group once the groups above it are done
+	for start := 1; start < len(order); {
+		end := start
+		for end < len(order) && nodes[order[end]].Level == nodes[order[start]].Level {
+			end++
+		}
+		if err := h.linkParallel(ctx, nodes, order[start:end], workers); err != nil {
+			h.resetBulk()
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// linkParallel links the nodes at positions group with up to workers
+// goroutines
+func (h *HNSW) linkParallel(ctx context.Context, nodes []*node.Node, group []int, workers int) error {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(group)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= len(group) {
+					return
+				}
+				n := nodes[group[i]]
+				h.link(n, denseQuery(n.Vector))
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// resetBulk empties the index after an interrupted bulk load
+func (h *HNSW) resetBulk() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.nodesMutex.Lock()
+	defer h.nodesMutex.Unlock()
+	h.nodes = make(map[int]*node.Node)
+	h.entryPoint, h.maxLevel, h.dimension = 0, 0, 0
+}
+
+// bulkOrder returns the positions of nodes in the order opts asks for
+func (h *HNSW) bulkOrder(nodes []*node.Node, opts BulkOptions) []int {
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+
+	switch opts.Order {
+	case OrderShuffle:
+		h.rngMutex.Lock()
+		h.rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		h.rngMutex.Unlock()
+	case OrderCluster:
+		cluster := h.clusterNodes(nodes)
+		sort.SliceStable(order, func(i, j int) bool {
+			return cluster[order[i]] < cluster[order[j]]
+		})
+	}
+	return order
+}
+
+// clusterNodes assigns each node to the nearest of about sqrt(len(nodes))
+// pivots sampled from nodes and returns the pivot index of each node
+func (h *HNSW) clusterNodes(nodes []*node.Node) []int {
+	pivots := min(int(math.Ceil(math.Sqrt(float64(len(nodes))))), maxClusterPivots)
+	h.rngMutex.Lock()
+	sample := h.rng.Perm(len(nodes))[:pivots]
+	h.rngMutex.Unlock()
+
+	cluster := make([]int, len(nodes))
+	for i, n := range nodes {
+		best := math.Inf(1)
+		for p, pos := range sample {
+			if dist := h.distFunc(n.Vector, nodes[pos].Vector); dist < best {
+				best, cluster[i] = dist, p
+			}
+		}
+	}
+	return cluster
+}
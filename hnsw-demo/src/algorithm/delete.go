@@ -0,0 +1,144 @@
+package algorithm
+
+import (
+	"fmt"
+)
+
+// Delete removes id from the index. The former neighbors of id are
+// reconnected among themselves with the heuristic selection, unless the
+// config sets DelayRebuild, in which case the edges to id are only dropped
+func (h *HNSW) Delete(id int) error {
+	return h.DeleteBatch([]int{id})
+}
+
+// DeleteBatch removes every id in ids as Delete does, scanning the graph
+// for edges to them once for the whole batch. If any id is missing,
+// nothing is deleted
+func (h *HNSW) DeleteBatch(ids []int) error {
+	return h.deleteNodes(ids, true)
+}
+
+// unlink removes ids as DeleteBatch does but without counting them in the
+// deleted total of Stats, to undo inserts that did not complete
+func (h *HNSW) unlink(ids ...int) error {
+	return h.deleteNodes(ids, false)
+}
+
+// deleteNodes implements DeleteBatch. Only counted deletions add to the
+// deleted total
+func (h *HNSW) deleteNodes(ids []int, counted bool) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.nodesMutex.Lock()
+	defer h.nodesMutex.Unlock()
+
+	removed := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if _, exists := h.nodes[id]; !exists && !removed[id] {
+			return fmt.Errorf("%w: %d", ErrNodeNotFound, id)
+		}
+		removed[id] = true
+	}
+
+	// Collect the neighborhood of each id on each level before it goes away
+	neighborhoods := make([][][]int, 0, len(removed))
+	topLevel := 0
+	for _, id := range ids {
+		deleted, exists := h.nodes[id]
+		if !exists {
+			continue // listed twice
+		}
+		level := deleted.GetLevel()
+		topLevel = max(topLevel, level)
+		levels := make([][]int, level+1)
+		for lc := 0; lc <= level; lc++ {
+			levels[lc], _ = deleted.GetNeighbors(lc)
+		}
+		neighborhoods = append(neighborhoods, levels)
+
+		deleted.MarkDeleted()
+		delete(h.nodes, id)
+		if counted {
+			h.deleted++
+		}
+	}
+
+	// Drop every edge pointing at a deleted id, including one-directional
+	// ones
+	for _, n := range h.nodes {
+		for lc := 0; lc <= min(topLevel, n.GetLevel()); lc++ {
+			neighbors, _ := n.GetNeighbors(lc)
+			if kept := withoutIDs(neighbors, removed); len(kept) != len(neighbors) {
+				n.SetNeighbors(lc, kept)
+			}
+		}
+	}
+
+	if !h.config.DelayRebuild {
+		for _, levels := range neighborhoods {
+			for lc, neighborhood := range levels {
+				h.reconnect(withoutIDs(neighborhood, removed), lc)
+			}
+		}
+	}
+
+	if removed[h.entryPoint] {
+		h.replaceEntryPoint()
+	}
+	return nil
+}
+
+// withoutIDs returns the ids of list that are not in removed, reusing
+// list when none are
+func withoutIDs(list []int, removed map[int]bool) []int {
+	for i, id := range list {
+		if !removed[id] {
+			continue
+		}
+		kept := append([]int(nil), list[:i]...)
+		for _, id := range list[i+1:] {
+			if !removed[id] {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}
+	return list
+}
+
+// reconnect gives each node in neighborhood new edges on level, chosen
+// from its remaining neighbors and the rest of neighborhood
+func (h *HNSW) reconnect(neighborhood []int, level int) {
+	for _, nodeID := range neighborhood {
+		n := h.nodes[nodeID]
+		current, _ := n.GetNeighbors(level)
+
+		candidates := make([]int, 0, len(current)+len(neighborhood))
+		candidates = append(candidates, current...)
+		for _, candidateID := range neighborhood {
+			if candidateID != nodeID {
+				candidates = append(candidates, candidateID)
+			}
+		}
+
+		selected := h.selectNeighborsHeuristic(h.nodeQuery(nodeID), candidates, h.maxDegree(level), level, false, true)
+		n.SetNeighbors(level, selected)
+		for _, neighborID := range selected {
+			h.addEdge(neighborID, nodeID, level)
+		}
+	}
+}
+
+// replaceEntryPoint makes the highest remaining node the entry point.
+// The caller must hold both locks
+func (h *HNSW) replaceEntryPoint() {
+	h.entryPoint, h.maxLevel = 0, 0
+	first := true
+	for id, n := range h.nodes {
+		level := n.GetLevel()
+		if first || level > h.maxLevel || (level == h.maxLevel && id < h.entryPoint) {
+			h.entryPoint, h.maxLevel = id, level
+			first = false
+		}
+	}
+}
@@ -1,15 +1,18 @@
 package algorithm
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/heap"
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/node"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
 )
 
 // HNSW represents the hierarchical navigable small world graph
@@ -18,83 +21,182 @@ type HNSW struct {
 	entryPoint int
 	maxLevel   int
 	config     config.Config
-	distFunc   distance.DistanceFunction
+	metric     distance.Metric
+	distFunc   distance.DistanceFunction // internal form of the metric
+	sparseDist sparse.DistanceFunction   // set for sparse indexes only
 	mutex      sync.RWMutex
 	nodesMutex sync.RWMutex
 	dimension  int
-	// deletedCount int
+	deleted    int // elements deleted since the index was created or loaded
+	defaultEf  int // ef of searches that do not set one, 0 for 2*K
+
+	// Striped locks serializing edge updates while BulkLoad links nodes in
+	// parallel, nil otherwise
+	edgeLocks []sync.Mutex
+
+	// Level generator, seeded from config.Seed
+	seed     int64
+	rng      *rand.Rand
+	rngMutex sync.Mutex
 }
 
-// New creates a new HNSW index
+// New creates a new HNSW index using the metric registered under the
+// given name
 func New(cfg config.Config, metric string) (*HNSW, error) {
-	distFunc, err := distance.GetDistanceFunction(metric)
+	m, err := distance.Lookup(metric)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get distance function: %v", err)
 	}
 
-	return &HNSW{
+	h := &HNSW{
 		nodes:    make(map[int]*node.Node),
 		config:   cfg,
-		distFunc: distFunc,
-	}, nil
+		metric:   m,
+		distFunc: m.InternalFunc(),
+	}
+	h.setSeed(cfg.Seed)
+	return h, nil
+}
+
+// setSeed reseeds the level generator, with a random seed if seed is zero
+func (h *HNSW) setSeed(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	h.rngMutex.Lock()
+	defer h.rngMutex.Unlock()
+	h.seed = seed
+	h.rng = rand.New(rand.NewSource(seed))
+}
+
+// Seed returns the seed of the level generator: config.Seed, or the random
+// seed picked in its place
+func (h *HNSW) Seed() int64 {
+	h.rngMutex.Lock()
+	defer h.rngMutex.Unlock()
+	return h.seed
+}
+
+// Metric returns the name of the index's distance metric
+func (h *HNSW) Metric() string {
+	return h.metric.Name
+}
+
+// Config returns the configuration the index was created with
+func (h *HNSW) Config() config.Config {
+	return h.config
+}
+
+// Dimension returns the dimension of the stored vectors, 0 while the index
+// is empty or stores sparse vectors
+func (h *HNSW) Dimension() int {
+	h.nodesMutex.RLock()
+	defer h.nodesMutex.RUnlock()
+	if len(h.nodes) == 0 {
+		return 0
+	}
+	return h.dimension
+}
+
+// MaxLevel returns the top layer of the graph
+func (h *HNSW) MaxLevel() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.maxLevel
+}
+
+// prepareVector normalizes v if the metric requires unit-length vectors
+func (h *HNSW) prepareVector(v []float64) []float64 {
+	if h.metric.Properties.RequiresNormalization {
+		return distance.NormalizeVector(v)
+	}
+	return v
+}
+
+// newDenseNode creates a node for vector at level. If the metric
+// normalizes vectors, the node searches with the normalized copy and keeps
+// the original for Get and IncludeVectors
+func (h *HNSW) newDenseNode(id int, vector []float64, level int) *node.Node {
+	if !h.metric.Properties.RequiresNormalization {
+		return node.NewNode(id, vector, level)
+	}
+	n := node.NewNode(id, h.prepareVector(vector), level)
+	n.Original = append([]float64(nil), vector...)
+	return n
 }
 
 // generateLevel generates random level for new nodes
 func (h *HNSW) generateLevel() int {
-	level := int(math.Floor(-math.Log(rand.Float64()) * h.config.ML))
-	if level > h.maxLevel {
-		h.mutex.Lock()
-		if level > h.maxLevel {
-			h.maxLevel = level
-		}
-		h.mutex.Unlock()
-	}
-	return level
+	h.rngMutex.Lock()
+	defer h.rngMutex.Unlock()
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.config.ML))
 }
 
 // Insert adds a new element to the index
 func (h *HNSW) Insert(id int, vector []float64) error {
+	return h.InsertWithAttributes(id, vector, nil)
+}
+
+// InsertWithAttributes adds a new element with an attached payload
+func (h *HNSW) InsertWithAttributes(id int, vector []float64, attrs map[string]string) error {
+	if h.IsSparse() {
+		return ErrSparseIndex
+	}
+
 	// Check if node already exists
 	h.nodesMutex.Lock()
 	if _, exists := h.nodes[id]; exists {
 		h.nodesMutex.Unlock()
-		return fmt.Errorf("node %d already exists", id)
+		return fmt.Errorf("%w: %d", ErrNodeExists, id)
 	}
 
 	// Dimension check
-    if len(h.nodes) == 0 {
-        // set dimension for the first node
-        h.dimension = len(vector)
-    } else if len(vector) != h.dimension {
-        h.nodesMutex.Unlock()
-        return fmt.Errorf("vector dimension mismatch: expected %d, got %d", h.dimension, len(vector))
-    }
+	if len(h.nodes) == 0 {
+		// set dimension for the first node
+		h.dimension = len(vector)
+	} else if len(vector) != h.dimension {
+		h.nodesMutex.Unlock()
+		return fmt.Errorf("%w: expected %d, got %d", ErrDimensionMismatch, h.dimension, len(vector))
+	}
 
 	// Create new node
 	level := h.generateLevel()
-	newNode := node.NewNode(id, vector, level)
+	newNode := h.newDenseNode(id, vector, level)
+	newNode.SetAttributes(attrs)
 	h.nodes[id] = newNode
 	h.nodesMutex.Unlock()
 
+	h.link(newNode, denseQuery(newNode.Vector))
+	return nil
+}
+
+// link connects a node that was just added to h.nodes into the graph
+func (h *HNSW) link(newNode *node.Node, q query) {
+	id, level := newNode.ID, newNode.Level
+
 	// Handle first node
 	h.mutex.Lock()
-	if h.entryPoint == 0 {
+	if len(h.nodes) == 1 {
 		h.entryPoint = id
+		h.maxLevel = level
 		h.mutex.Unlock()
-		return nil
+		return
 	}
+	currObj := h.entryPoint
+	maxLevel := h.maxLevel
 	h.mutex.Unlock()
 
 	// Search for insert
-	currObj := h.entryPoint
-	for lc := h.maxLevel; lc > level; lc-- {
+	for lc := maxLevel; lc > level; lc-- {
 		changed := false
-		currNode := h.nodes[currObj]
+		currDist := h.distanceTo(q, currObj)
 
 		// Find better point to start from
-		for _, neighbor := range currNode.Neighbors[lc] {
-			if dist := h.distFunc(vector, h.nodes[neighbor].GetVector()); dist < h.distFunc(vector, currNode.GetVector()) {
+		neighbors, _ := h.nodes[currObj].GetNeighbors(lc)
+		for _, neighbor := range neighbors {
+			if dist := h.distanceTo(q, neighbor); dist < currDist {
 				currObj = neighbor
+				currDist = dist
 				changed = true
 			}
 		}
@@ -104,121 +206,216 @@ func (h *HNSW) Insert(id int, vector []float64) error {
 	}
 
 	// Connect on each level
-	for lc := min(level, h.maxLevel); lc >= 0; lc-- {
+	for lc := min(level, maxLevel); lc >= 0; lc-- {
 		// Find candidates
-		candidates := h.searchLayer(vector, currObj, h.config.EfConstruction, lc)
+		candidates := h.searchLayer(q, currObj, h.config.EfConstruction, lc)
 
 		// Select neighbors
-		neighbors := h.selectNeighborsHeuristic(vector, candidates, h.config.M, lc, true, true)
+		neighbors := h.selectNeighborsHeuristic(q, candidates, h.config.M, lc, true, true)
 
 		// Add connections
 		for _, neighborID := range neighbors {
-			newNode.AddNeighbor(lc, neighborID)
-			h.nodes[neighborID].AddNeighbor(lc, id)
+			h.addEdge(id, neighborID, lc)
+			h.addEdge(neighborID, id, lc)
 		}
 
-		currObj = id
+		// Continue from the nearest element found on this level
+		currObj = candidates[0]
 	}
 
-	return nil
+	// Promote the new node to entry point if it tops the graph
+	if level > maxLevel {
+		h.mutex.Lock()
+		h.entryPoint = id
+		h.maxLevel = level
+		h.mutex.Unlock()
+	}
+}
+
+// maxDegree returns the maximum number of neighbors a node keeps on level:
+// MaxM on the bottom layer and M above it
+func (h *HNSW) maxDegree(level int) int {
+	if level == 0 {
+		return h.config.MaxM
+	}
+	return h.config.M
+}
+
+// addEdge adds the edge from -> to on level, shrinking the neighbors of
+// from with the heuristic selection if they exceed maxDegree(level)
+func (h *HNSW) addEdge(from, to, level int) {
+	if h.edgeLocks != nil {
+		lock := &h.edgeLocks[from%len(h.edgeLocks)]
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	n := h.nodes[from]
+	n.AddNeighbor(level, to)
+
+	neighbors, _ := n.GetNeighbors(level)
+	if maxM := h.maxDegree(level); len(neighbors) > maxM {
+		n.SetNeighbors(level, h.selectNeighborsHeuristic(h.nodeQuery(from), neighbors, maxM, level, false, true))
+	}
 }
 
+// ctxCheckInterval is how many candidate expansions searchLayer performs
+// between checks of its context
+const ctxCheckInterval = 16
+
 // searchLayer implements layer-wise search
-func (h *HNSW) searchLayer(q []float64, entryPointID int, ef int, level int) []int {
+func (h *HNSW) searchLayer(q query, entryPointID int, ef int, level int) []int {
+	items, _ := h.searchLayerBounded(newSearchState(context.Background(), SearchOptions{}), q, entryPointID, ef, level)
+	return itemIDs(items)
+}
+
+// searchLayerBounded implements layer-wise search within the context and
+// work budget of st. If ctx is done before the search converges, it returns
+// the best results found so far together with ctx.Err(); if the budget runs
+// out, it returns them with a nil error and marks st as truncated. Results
+// are ordered from nearest to furthest
+func (h *HNSW) searchLayerBounded(st *searchState, q query, entryPointID int, ef int, level int) ([]heap.Item, error) {
 	visited := make(map[int]bool)
 	candidates := heap.NewPriorityQueue()
-	results := heap.NewPriorityQueue()
+	results := heap.NewMaxPriorityQueue()
 
-	dist := h.distFunc(q, h.nodes[entryPointID].GetVector())
+	st.countDistance()
+	dist := h.distanceTo(q, entryPointID)
 	candidates.PushItem(entryPointID, dist)
-	results.PushItem(entryPointID, dist)
+	if st.accepts(level, entryPointID) {
+		results.PushItem(entryPointID, dist)
+	}
 	visited[entryPointID] = true
+	st.visit()
+
+	var err error
+search:
+	for steps := 0; candidates.Len() > 0; steps++ {
+		if steps%ctxCheckInterval == 0 {
+			if err = st.ctx.Err(); err != nil {
+				break
+			}
+		}
 
-	for candidates.Len() > 0 {
-		// Fix PopItem usage
 		nodeID, nodeDist := candidates.PopItem()
 
-		// Fix Top usage
-		furthest, exists := results.Top()
-		if exists && nodeDist > furthest.Distance {
+		// All remaining candidates are further than the furthest result.
+		// With a filter, keep going until enough accepted results are found
+		furthest, ok := results.Top()
+		if ok && nodeDist > furthest.Distance && (results.Len() >= ef || !st.filtering(level)) {
 			break
 		}
+		st.stats.Hops++
 
 		neighbors, _ := h.nodes[nodeID].GetNeighbors(level)
 		for _, neighborID := range neighbors {
 			if !visited[neighborID] {
+				if st.exhausted() {
+					break search
+				}
 				visited[neighborID] = true
-				dist := h.distFunc(q, h.nodes[neighborID].GetVector())
+				st.visit()
+				st.countDistance()
+				dist := h.distanceTo(q, neighborID)
 
-				furthest, exists := results.Top()
-				if !exists || results.Len() < ef || dist < furthest.Distance {
+				furthest, ok := results.Top()
+				if !ok || results.Len() < ef || dist < furthest.Distance {
 					candidates.PushItem(neighborID, dist)
-					results.PushItem(neighborID, dist)
-					if results.Len() > ef {
-						results.Pop()
+					if st.accepts(level, neighborID) {
+						results.PushItem(neighborID, dist)
+						if results.Len() > ef {
+							results.PopItem()
+						}
 					}
 				}
 			}
 		}
 	}
 
-	resultIds := make([]int, 0, results.Len())
-	for results.Len() > 0 {
-		id, _ := results.PopItem()
-		resultIds = append(resultIds, id)
+	// Drain the max-heap back to front so items end up nearest first
+	items := make([]heap.Item, results.Len())
+	for i := len(items) - 1; i >= 0; i-- {
+		items[i].NodeID, items[i].Distance = results.PopItem()
 	}
-	return resultIds
+	return items, err
 }
 
-// Search performs K-NN search
-func (h *HNSW) Search(q []float64, k int) ([]int, []float64) {
-	if len(h.nodes) == 0 {
-		return nil, nil
+// itemIDs returns the node IDs of items in order
+func itemIDs(items []heap.Item) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.NodeID
 	}
+	return ids
+}
 
-	// Dimension check
-	if len(q) != h.dimension {
-        return nil, nil
-    }
-
-	h.mutex.RLock()
-	ep := h.entryPoint
-	currentLevel := h.maxLevel
-	h.mutex.RUnlock()
+// Search returns the nearest neighbors of q selected by opts, nearest first.
+// An empty index yields an empty result. If ctx is done before the search
+// finishes, the best partial results found so far are returned together
+// with ctx.Err()
+func (h *HNSW) Search(ctx context.Context, q []float64, opts SearchOptions) ([]Result, error) {
+	results, _, err := h.SearchWithStats(ctx, q, opts)
+	return results, err
+}
 
-	currObj := ep
-	for level := currentLevel; level > 0; level-- {
-		changed := false
-		currNode := h.nodes[currObj]
+// SearchWithStats is Search that also reports the work the query used
+func (h *HNSW) SearchWithStats(ctx context.Context, q []float64, opts SearchOptions) ([]Result, SearchStats, error) {
+	if h.IsSparse() {
+		return []Result{}, SearchStats{}, ErrSparseIndex
+	}
+	if len(h.nodes) > 0 && len(q) != h.dimension {
+		return []Result{}, SearchStats{}, fmt.Errorf("%w: expected %d, got %d", ErrDimensionMismatch, h.dimension, len(q))
+	}
+	return h.search(ctx, denseQuery(h.prepareVector(q)), opts)
+}
 
-		// Find better point to start from
-		for _, neighbor := range currNode.Neighbors[level] {
-			if dist := h.distFunc(q, h.nodes[neighbor].GetVector()); dist < h.distFunc(q, currNode.GetVector()) {
-				currObj = neighbor
-				changed = true
-			}
-		}
-		if !changed {
-			break
-		}
+// search runs a query validated by the caller
+func (h *HNSW) search(ctx context.Context, q query, opts SearchOptions) ([]Result, SearchStats, error) {
+	if opts.K <= 0 {
+		return []Result{}, SearchStats{}, fmt.Errorf("K must be positive, got %d", opts.K)
+	}
+	if len(h.nodes) == 0 {
+		return []Result{}, SearchStats{}, nil
 	}
 
-	// Search at layer 0
-	candidates := h.searchLayer(q, currObj, k*2, 0)
+	ef := opts.Ef
+	if ef <= 0 {
+		ef = h.DefaultEf()
+	}
+	if ef <= 0 {
+		ef = 2 * opts.K
+	} else if ef < opts.K {
+		ef = opts.K
+	}
 
-	// Get k nearest
-	results := make([]int, 0, k)
-	distances := make([]float64, 0, k)
+	st := newSearchState(ctx, opts)
+	items, err := h.searchBottomLayer(st, q, ef)
 
-	for _, id := range candidates {
-		if len(results) >= k {
+	results := make([]Result, 0, min(opts.K, len(items)))
+	for _, item := range items {
+		// Convert from the internal form of the metric at the API boundary
+		dist := h.metric.FinalizeDistance(item.Distance)
+		if len(results) >= opts.K || (opts.MaxDistance != 0 && dist > opts.MaxDistance) {
 			break
 		}
-		results = append(results, id)
-		distances = append(distances, h.distFunc(q, h.nodes[id].GetVector()))
+		// Partial results from an interrupted descent are not filtered yet
+		if !st.accepts(0, item.NodeID) {
+			continue
+		}
+		result := Result{ID: item.NodeID, Distance: dist}
+		if opts.IncludeVectors {
+			if h.IsSparse() {
+				result.Sparse = h.nodes[item.NodeID].GetSparse()
+			} else {
+				result.Vector = h.nodes[item.NodeID].GetInsertedVector()
+			}
+		}
+		if opts.IncludeAttributes {
+			result.Attributes = h.nodes[item.NodeID].GetAttributes()
+		}
+		results = append(results, result)
 	}
-
-	return results, distances
+	return results, st.stats, err
 }
 
 func min(a, b int) int {
@@ -0,0 +1,244 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+)
+
+// Fusion selects how a hybrid query combines the dense and sparse rankings
+type Fusion int
+
+const (
+	// FusionRRF scores each hit by reciprocal-rank fusion,
+	// sum of weight / (RRFK + rank) over the sources that returned it
+	FusionRRF Fusion = iota
+
+	// FusionWeightedSum scores each hit by the weighted sum of its per-source
+	// scores, where distances are min-max normalized to [0, 1] per source
+	// with 1 for the nearest hit
+	FusionWeightedSum
+)
+
+// DefaultRRFK is the rank constant used by FusionRRF when HybridOptions.RRFK is 0
+const DefaultRRFK = 60
+
+// Hybrid pairs a dense and a sparse index over the same ids
+type Hybrid struct {
+	dense  *HNSW
+	sparse *HNSW
+}
+
+// HybridOptions controls a single hybrid query
+type HybridOptions struct {
+	// Number of fused results to return
+	K int
+
+	// Number of hits retrieved from each source before fusion, defaults to 2*K
+	CandidateK int
+
+	// Size of the candidate list of each source search, defaults to 2*CandidateK
+	Ef int
+
+	// Fusion method, FusionRRF by default
+	Fusion Fusion
+
+	// Weights of the dense and sparse sources. Both 0 weighs them equally
+	DenseWeight  float64
+	SparseWeight float64
+
+	// Rank constant for FusionRRF, defaults to DefaultRRFK
+	RRFK int
+
+	// Only ids for which Filter returns true are returned, nil accepts all
+	Filter func(id int) bool
+}
+
+// SourceScore is the contribution of one source to a hybrid hit
+type SourceScore struct {
+	// Whether the source returned the hit
+	Found bool
+
+	// 1-based rank and distance within the source, 0 if not found
+	Rank     int
+	Distance float64
+
+	// Unweighted score used for fusion, 0 if not found
+	Score float64
+}
+
+// HybridResult is a single fused hit, ranked by Score
+type HybridResult struct {
+	ID     int
+	Score  float64
+	Dense  SourceScore
+	Sparse SourceScore
+}
+
+// NewHybrid creates a hybrid index with a dense index using denseMetric and
+// a sparse index using sparseMetric
+func NewHybrid(cfg config.Config, denseMetric, sparseMetric string) (*Hybrid, error) {
+	dense, err := New(cfg, denseMetric)
+	if err != nil {
+		return nil, err
+	}
+	sparseIndex, err := NewSparse(cfg, sparseMetric)
+	if err != nil {
+		return nil, err
+	}
+	return &Hybrid{dense: dense, sparse: sparseIndex}, nil
+}
+
+// Dense returns the dense index
+func (hy *Hybrid) Dense() *HNSW {
+	return hy.dense
+}
+
+// Sparse returns the sparse index
+func (hy *Hybrid) Sparse() *HNSW {
+	return hy.sparse
+}
+
+// Insert adds an element to both indexes. A nil dense vector or an empty
+// sparse vector leaves the element out of that source only. If either
+// insert fails the element is left out of both, and an undone dense
+// insert does not count as a deletion
+func (hy *Hybrid) Insert(id int, dense []float64, sv sparse.Vector) error {
+	if dense == nil && sv.Len() == 0 {
+		return errors.New("element has neither a dense nor a sparse vector")
+	}
+	if sv.Len() > 0 {
+		if err := sv.Validate(); err != nil {
+			return fmt.Errorf("invalid sparse vector: %v", err)
+		}
+	}
+
+	// Reject duplicates before touching either index
+	if _, err := hy.dense.Get(id); err == nil {
+		return fmt.Errorf("%w: %d", ErrNodeExists, id)
+	}
+	if _, err := hy.sparse.Get(id); err == nil {
+		return fmt.Errorf("%w: %d", ErrNodeExists, id)
+	}
+
+	if dense != nil {
+		if err := hy.dense.Insert(id, dense); err != nil {
+			return err
+		}
+	}
+	if sv.Len() > 0 {
+		if err := hy.sparse.InsertSparse(id, sv); err != nil {
+			// Keep the element out of both sources rather than one
+			if dense != nil {
+				if undoErr := hy.dense.unlink(id); undoErr != nil {
+					return errors.Join(err, fmt.Errorf("failed to undo the dense insert: %w", undoErr))
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Search runs a dense search for dq and a sparse search for sq and fuses
+// the two rankings. Either query may be omitted (nil or empty), in which
+// case only the other source contributes
+func (hy *Hybrid) Search(ctx context.Context, dq []float64, sq sparse.Vector, opts HybridOptions) ([]HybridResult, error) {
+	if opts.K <= 0 {
+		return []HybridResult{}, fmt.Errorf("K must be positive, got %d", opts.K)
+	}
+	if opts.CandidateK < opts.K {
+		opts.CandidateK = 2 * opts.K
+	}
+	if opts.RRFK <= 0 {
+		opts.RRFK = DefaultRRFK
+	}
+	if opts.DenseWeight == 0 && opts.SparseWeight == 0 {
+		opts.DenseWeight, opts.SparseWeight = 1, 1
+	}
+
+	sourceOpts := SearchOptions{K: opts.CandidateK, Ef: opts.Ef, Filter: opts.Filter}
+
+	var denseResults, sparseResults []Result
+	if dq != nil {
+		results, err := hy.dense.Search(ctx, dq, sourceOpts)
+		if err != nil {
+			return []HybridResult{}, fmt.Errorf("dense search: %w", err)
+		}
+		denseResults = results
+	}
+	if sq.Len() > 0 {
+		results, err := hy.sparse.SearchSparse(ctx, sq, sourceOpts)
+		if err != nil {
+			return []HybridResult{}, fmt.Errorf("sparse search: %w", err)
+		}
+		sparseResults = results
+	}
+
+	fused := make(map[int]*HybridResult)
+	hit := func(id int) *HybridResult {
+		r, ok := fused[id]
+		if !ok {
+			r = &HybridResult{ID: id}
+			fused[id] = r
+		}
+		return r
+	}
+
+	denseScores := sourceScores(denseResults, opts)
+	for i, result := range denseResults {
+		r := hit(result.ID)
+		r.Dense = denseScores[i]
+		r.Score += opts.DenseWeight * r.Dense.Score
+	}
+	sparseScores := sourceScores(sparseResults, opts)
+	for i, result := range sparseResults {
+		r := hit(result.ID)
+		r.Sparse = sparseScores[i]
+		r.Score += opts.SparseWeight * r.Sparse.Score
+	}
+
+	results := make([]HybridResult, 0, len(fused))
+	for _, r := range fused {
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > opts.K {
+		results = results[:opts.K]
+	}
+	return results, nil
+}
+
+// sourceScores scores one source's results, nearest first, for fusion
+func sourceScores(results []Result, opts HybridOptions) []SourceScore {
+	scores := make([]SourceScore, len(results))
+	if len(results) == 0 {
+		return scores
+	}
+
+	nearest := results[0].Distance
+	furthest := results[len(results)-1].Distance
+	for i, result := range results {
+		score := SourceScore{Found: true, Rank: i + 1, Distance: result.Distance}
+		switch opts.Fusion {
+		case FusionWeightedSum:
+			score.Score = 1
+			if furthest > nearest {
+				score.Score = (furthest - result.Distance) / (furthest - nearest)
+			}
+		default:
+			score.Score = 1 / float64(opts.RRFK+score.Rank)
+		}
+		scores[i] = score
+	}
+	return scores
+}
@@ -1,61 +1,93 @@
 package algorithm
 
+import (
+	"context"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/heap"
+)
+
 // KNNSearch implements k-nearest neighbor search
 func (h *HNSW) KNNSearch(q []float64, K int, ef int) []int {
-	// Check if graph is empty
-	if len(h.nodes) == 0 {
-		return []int{}
+	results, _ := h.KNNSearchContext(context.Background(), q, K, ef)
+	return results
+}
+
+// KNNSearchContext is KNNSearch bounded by ctx. If ctx is done before the
+// search finishes, it returns the best partial results found so far together
+// with ctx.Err()
+func (h *HNSW) KNNSearchContext(ctx context.Context, q []float64, K int, ef int) ([]int, error) {
+	results, err := h.Search(ctx, q, SearchOptions{K: K, Ef: ef})
+	ids, _ := splitResults(results)
+	return ids, err
+}
+
+// KNNSearchWithDistances returns K nearest neighbors with distances
+func (h *HNSW) KNNSearchWithDistances(q []float64, K int, ef int) ([]int, []float64) {
+	results, _ := h.Search(context.Background(), q, SearchOptions{K: K, Ef: ef})
+	return splitResults(results)
+}
+
+// RangeSearch returns all elements within radius of q, nearest first.
+// ef is the initial size of the candidate list; it is doubled until the
+// furthest candidate falls outside the radius
+func (h *HNSW) RangeSearch(q []float64, radius float64, ef int) ([]int, []float64) {
+	ids, distances, _ := h.RangeSearchContext(context.Background(), q, radius, ef)
+	return ids, distances
+}
+
+// RangeSearchContext is RangeSearch bounded by ctx. If ctx is done before
+// the search finishes, it returns the elements within radius found so far
+// together with ctx.Err()
+func (h *HNSW) RangeSearchContext(ctx context.Context, q []float64, radius float64, ef int) ([]int, []float64, error) {
+	if ef < 1 {
+		ef = 1
 	}
 
-	// Dimension check
-    if len(h.nodes) == 0 {
-        return []int{}
-    }
-    
-    if len(q) != h.dimension {
-        return []int{}
-    }
+	var (
+		results []Result
+		err     error
+	)
+	for {
+		results, err = h.Search(ctx, q, SearchOptions{K: ef, Ef: ef})
+		if err != nil || len(results) < ef || ef >= len(h.nodes) {
+			break
+		}
+		// Stop growing once the candidate list reaches past the radius
+		if results[len(results)-1].Distance > radius {
+			break
+		}
+		ef *= 2
+	}
 
+	// Drop the candidates outside the radius
+	n := 0
+	for n < len(results) && results[n].Distance <= radius {
+		n++
+	}
+	ids, distances := splitResults(results[:n])
+	return ids, distances, err
+}
+
+// searchBottomLayer descends from the entry point with ef=1 on the upper
+// layers and returns the ef nearest elements found on layer 0
+func (h *HNSW) searchBottomLayer(st *searchState, q query, ef int) ([]heap.Item, error) {
 	// Get entry point
 	h.mutex.RLock()
 	ep := h.entryPoint
 	currentLevel := h.maxLevel
 	h.mutex.RUnlock()
 
-	if ep == 0 {
-		return []int{}
-	}
-
 	// Search from top layer
 	currObj := ep
 	for level := currentLevel; level >= 1; level-- {
 		// Search layer with ef=1 to find better entry point
-		candidates := h.searchLayer(q, currObj, 1, level)
-		if len(candidates) > 0 {
-			currObj = candidates[0]
+		candidates, err := h.searchLayerBounded(st, q, currObj, 1, level)
+		if err != nil || st.exhausted() {
+			return candidates, err
 		}
+		currObj = candidates[0].NodeID
 	}
 
 	// Search bottom layer with specified ef
-	finalResults := h.searchLayer(q, currObj, ef, 0)
-
-	// Return K nearest elements
-	if K > len(finalResults) {
-		K = len(finalResults)
-	}
-	return finalResults[:K]
-}
-
-// KNNSearchWithDistances returns K nearest neighbors with distances
-func (h *HNSW) KNNSearchWithDistances(q []float64, K int, ef int) ([]int, []float64) {
-	// Get K nearest neighbors
-	ids := h.KNNSearch(q, K, ef)
-
-	// Calculate distances
-	distances := make([]float64, len(ids))
-	for i, id := range ids {
-		distances[i] = h.distFunc(q, h.nodes[id].GetVector())
-	}
-
-	return ids, distances
+	return h.searchLayerBounded(st, q, currObj, ef, 0)
 }
@@ -0,0 +1,131 @@
+package algorithm
+
+import (
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/node"
+)
+
+// Merge returns a new index over the union of the elements of a and b,
+// which are left unchanged. Both must use the same metric and hold
+// vectors of the same kind and dimension, and no id may appear in both.
+// The existing neighbor lists are kept as candidates; each element also
+// searches the other index for candidates on every layer it shares with
+// it, and the heuristic selection picks its new neighbors among them.
+// Elements the re-selection leaves unreachable are reconnected as by
+// Repair. The merged index uses the config of a and the 2*K default ef
+func Merge(a, b *HNSW) (*HNSW, error) {
+	if a == b {
+		return nil, fmt.Errorf("cannot merge an index with itself")
+	}
+	if a.metric.Name != b.metric.Name {
+		return nil, fmt.Errorf("cannot merge a %s index with a %s index", a.metric.Name, b.metric.Name)
+	}
+	if a.IsSparse() != b.IsSparse() {
+		return nil, fmt.Errorf("cannot merge a sparse index with a dense index")
+	}
+	if da, db := a.Dimension(), b.Dimension(); da != 0 && db != 0 && da != db {
+		return nil, fmt.Errorf("%w: %d and %d", ErrDimensionMismatch, da, db)
+	}
+
+	for _, h := range []*HNSW{a, b} {
+		h.mutex.RLock()
+		defer h.mutex.RUnlock()
+		h.nodesMutex.RLock()
+		defer h.nodesMutex.RUnlock()
+	}
+
+	for _, id := range a.sortedIDs() {
+		if _, exists := b.nodes[id]; exists {
+			return nil, fmt.Errorf("%w: %d", ErrNodeExists, id)
+		}
+	}
+
+	newIndex := New
+	if a.IsSparse() {
+		newIndex = NewSparse
+	}
+	merged, err := newIndex(a.config, a.metric.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range []*HNSW{a, b} {
+		for id, n := range h.nodes {
+			merged.nodes[id] = copyNode(n, h.IsSparse())
+		}
+		if len(h.nodes) > 0 && merged.dimension == 0 {
+			merged.dimension = h.dimension
+		}
+	}
+	switch {
+	case len(a.nodes) == 0 && len(b.nodes) == 0:
+		return merged, nil
+	case len(b.nodes) == 0 || (len(a.nodes) > 0 && a.maxLevel >= b.maxLevel):
+		merged.entryPoint, merged.maxLevel = a.entryPoint, a.maxLevel
+	default:
+		merged.entryPoint, merged.maxLevel = b.entryPoint, b.maxLevel
+	}
+	if len(a.nodes) == 0 || len(b.nodes) == 0 {
+		return merged, nil
+	}
+
+	// Search the other index for every element before any edge changes,
+	// so that each search sees one of the original graphs
+	cross := make(map[int][][]int, len(merged.nodes))
+	for _, pair := range [][2]*HNSW{{a, b}, {b, a}} {
+		from, other := pair[0], pair[1]
+		for id, n := range from.nodes {
+			cross[id] = other.layerCandidates(from.nodeQuery(id), n.GetLevel())
+		}
+	}
+
+	for _, id := range merged.sortedIDs() {
+		q := merged.nodeQuery(id)
+		for lc, found := range cross[id] {
+			current, _ := merged.nodes[id].GetNeighbors(lc)
+			candidates := append(current, found...)
+			selected := merged.selectNeighborsHeuristic(q, candidates, merged.maxDegree(lc), lc, false, true)
+			merged.nodes[id].SetNeighbors(lc, selected)
+			for _, neighborID := range selected {
+				merged.addEdge(neighborID, id, lc)
+			}
+		}
+	}
+
+	merged.Repair()
+	return merged, nil
+}
+
+// layerCandidates returns, for each layer from 0 up to the lower of level
+// and the top layer, the EfConstruction closest elements to q found by
+// searching that layer. The caller must hold both locks
+func (h *HNSW) layerCandidates(q query, level int) [][]int {
+	top := min(level, h.maxLevel)
+	candidates := make([][]int, top+1)
+	curr := h.greedyClosest(q, top)
+	for lc := top; lc >= 0; lc-- {
+		candidates[lc] = h.searchLayer(q, curr, h.config.EfConstruction, lc)
+		curr = candidates[lc][0]
+	}
+	return candidates
+}
+
+// copyNode returns a copy of n with its own vector, attributes and
+// neighbor lists
+func copyNode(n *node.Node, isSparse bool) *node.Node {
+	var c *node.Node
+	if isSparse {
+		c = node.NewSparseNode(n.ID, n.GetSparse(), n.GetLevel())
+	} else {
+		c = node.NewNode(n.ID, n.GetVector(), n.GetLevel())
+		if n.Original != nil {
+			c.Original = n.GetInsertedVector()
+		}
+	}
+	c.SetAttributes(n.GetAttributes())
+	for level, neighbors := range n.GetAllNeighbors() {
+		c.Neighbors[level] = neighbors
+	}
+	return c
+}
@@ -0,0 +1,252 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+)
+
+// ErrDocumentNotFound is returned when a document key is not present
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Aggregation selects how chunk similarities are combined into a
+// document score
+type Aggregation int
+
+const (
+	// AggregateMax scores a document by its most similar chunk
+	AggregateMax Aggregation = iota
+
+	// AggregateSumTopN scores a document by the sum of the similarities of
+	// its TopN most similar chunks
+	AggregateSumTopN
+)
+
+// MultiVector is an index of documents that each own several vectors,
+// such as the embeddings of a document's chunks. Queries search all chunk
+// vectors and return whole documents
+type MultiVector struct {
+	index  *HNSW
+	mutex  sync.RWMutex
+	docs   map[string][]int // document key -> chunk ids
+	chunks map[int]chunkRef // chunk id -> owning document
+	nextID int
+}
+
+// chunkRef locates a chunk vector within its document
+type chunkRef struct {
+	key   string
+	index int
+}
+
+// MultiVectorOptions controls a single document query
+type MultiVectorOptions struct {
+	// Number of documents to return
+	K int
+
+	// Number of chunks retrieved before aggregation, defaults to 4*K. It
+	// is doubled until K distinct documents are found
+	ChunkK int
+
+	// Size of the candidate list of the chunk search, defaults to 2*ChunkK
+	Ef int
+
+	// Aggregation method, AggregateMax by default
+	Aggregation Aggregation
+
+	// Number of chunks summed by AggregateSumTopN, defaults to 3
+	TopN int
+}
+
+// ChunkMatch is a chunk that contributed to a document hit
+type ChunkMatch struct {
+	// Position of the chunk among the vectors added for the document
+	Index    int
+	Distance float64
+}
+
+// DocumentResult is a single document hit, ranked by Score
+type DocumentResult struct {
+	Key string
+
+	// Aggregated similarity, higher is better
+	Score float64
+
+	// Retrieved chunks of the document, nearest first
+	Chunks []ChunkMatch
+}
+
+// NewMultiVector creates a multi-vector index using the metric registered
+// under the given name
+func NewMultiVector(cfg config.Config, metric string) (*MultiVector, error) {
+	index, err := New(cfg, metric)
+	if err != nil {
+		return nil, err
+	}
+	return &MultiVector{
+		index:  index,
+		docs:   make(map[string][]int),
+		chunks: make(map[int]chunkRef),
+	}, nil
+}
+
+// Index returns the underlying chunk index
+func (mv *MultiVector) Index() *HNSW {
+	return mv.index
+}
+
+// Len returns the number of documents
+func (mv *MultiVector) Len() int {
+	mv.mutex.RLock()
+	defer mv.mutex.RUnlock()
+	return len(mv.docs)
+}
+
+// Add appends vectors to the document key, creating it if needed. If a
+// vector is rejected, none of the vectors are added
+func (mv *MultiVector) Add(key string, vectors ...[]float64) error {
+	if len(vectors) == 0 {
+		return fmt.Errorf("no vectors for document %q", key)
+	}
+
+	mv.mutex.Lock()
+	defer mv.mutex.Unlock()
+
+	first := mv.nextID
+	for i, vector := range vectors {
+		id := first + i
+		if err := mv.index.Insert(id, vector); err != nil {
+			added := make([]int, 0, i)
+			for prev := first; prev < id; prev++ {
+				added = append(added, prev)
+			}
+			err = fmt.Errorf("document %q: %w", key, err)
+			if undoErr := mv.index.unlink(added...); undoErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to undo the inserted chunks: %w", undoErr))
+			}
+			return err
+		}
+	}
+
+	mv.nextID = first + len(vectors)
+	for id := first; id < mv.nextID; id++ {
+		mv.chunks[id] = chunkRef{key: key, index: len(mv.docs[key])}
+		mv.docs[key] = append(mv.docs[key], id)
+	}
+	return nil
+}
+
+// Delete removes the document key together with all of its vectors
+func (mv *MultiVector) Delete(key string) error {
+	mv.mutex.Lock()
+	defer mv.mutex.Unlock()
+
+	ids, exists := mv.docs[key]
+	if !exists {
+		return fmt.Errorf("%w: %q", ErrDocumentNotFound, key)
+	}
+
+	// Either every chunk goes or none does
+	if err := mv.index.DeleteBatch(ids); err != nil {
+		return fmt.Errorf("document %q: %w", key, err)
+	}
+	for _, id := range ids {
+		delete(mv.chunks, id)
+	}
+	delete(mv.docs, key)
+	return nil
+}
+
+// Search returns the K documents whose chunks are most similar to q
+func (mv *MultiVector) Search(ctx context.Context, q []float64, opts MultiVectorOptions) ([]DocumentResult, error) {
+	if opts.K <= 0 {
+		return []DocumentResult{}, fmt.Errorf("K must be positive, got %d", opts.K)
+	}
+	if opts.ChunkK < opts.K {
+		opts.ChunkK = 4 * opts.K
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = 3
+	}
+
+	mv.mutex.RLock()
+	defer mv.mutex.RUnlock()
+
+	var (
+		results []Result
+		err     error
+	)
+	for {
+		ef := opts.Ef
+		if ef < opts.ChunkK {
+			ef = 2 * opts.ChunkK
+		}
+		results, err = mv.index.Search(ctx, q, SearchOptions{K: opts.ChunkK, Ef: ef})
+		if err != nil || len(results) < opts.ChunkK || mv.countDocuments(results) >= opts.K {
+			break
+		}
+		opts.ChunkK *= 2
+	}
+
+	// Group chunks by document, keeping them nearest first
+	byDoc := make(map[string]*DocumentResult)
+	for _, result := range results {
+		ref := mv.chunks[result.ID]
+		doc, ok := byDoc[ref.key]
+		if !ok {
+			doc = &DocumentResult{Key: ref.key}
+			byDoc[ref.key] = doc
+		}
+		doc.Chunks = append(doc.Chunks, ChunkMatch{Index: ref.index, Distance: result.Distance})
+	}
+
+	docs := make([]DocumentResult, 0, len(byDoc))
+	for _, doc := range byDoc {
+		n := 1
+		if opts.Aggregation == AggregateSumTopN {
+			n = min(opts.TopN, len(doc.Chunks))
+		}
+		for _, chunk := range doc.Chunks[:n] {
+			doc.Score += similarity(mv.index.Metric(), chunk.Distance)
+		}
+		docs = append(docs, *doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Score != docs[j].Score {
+			return docs[i].Score > docs[j].Score
+		}
+		return docs[i].Key < docs[j].Key
+	})
+	if len(docs) > opts.K {
+		docs = docs[:opts.K]
+	}
+	return docs, err
+}
+
+// countDocuments returns the number of distinct documents among results
+func (mv *MultiVector) countDocuments(results []Result) int {
+	seen := make(map[string]bool)
+	for _, result := range results {
+		seen[mv.chunks[result.ID].key] = true
+	}
+	return len(seen)
+}
+
+// similarity converts a distance of the named metric into a similarity
+// where higher is better. Cosine and dot product map back to the cosine
+// and the inner product, other metrics to 1 / (1 + d)
+func similarity(metric string, d float64) float64 {
+	switch metric {
+	case distance.Cosine, distance.NormalizedCosine:
+		return 1 - d
+	case distance.DotProduct:
+		return -d
+	default:
+		return 1 / (1 + d)
+	}
+}
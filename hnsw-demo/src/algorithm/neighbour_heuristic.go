@@ -3,7 +3,7 @@ package algorithm
 import "github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/heap"
 
 // selectNeighborsHeuristic implements neighbor selection with heuristic algorithm
-func (h *HNSW) selectNeighborsHeuristic(q []float64, candidates []int, M int,
+func (h *HNSW) selectNeighborsHeuristic(q query, candidates []int, M int,
 	level int, extendCandidates bool, keepPrunedConnections bool) []int {
 
 	// Create working queue W
@@ -13,7 +13,7 @@ func (h *HNSW) selectNeighborsHeuristic(q []float64, candidates []int, M int,
 	// Add all candidates to working queue
 	for _, candidateID := range candidates {
 		if !visited[candidateID] {
-			dist := h.distFunc(q, h.nodes[candidateID].GetVector())
+			dist := h.distanceTo(q, candidateID)
 			workingQueue.PushItem(candidateID, dist)
 			visited[candidateID] = true
 		}
@@ -29,7 +29,7 @@ func (h *HNSW) selectNeighborsHeuristic(q []float64, candidates []int, M int,
 			neighbors, _ := h.nodes[candidateID].GetNeighbors(level)
 			for _, neighborID := range neighbors {
 				if !visited[neighborID] {
-					dist := h.distFunc(q, h.nodes[neighborID].GetVector())
+					dist := h.distanceTo(q, neighborID)
 					tempCandidates.PushItem(neighborID, dist)
 					visited[neighborID] = true
 				}
@@ -56,7 +56,7 @@ func (h *HNSW) selectNeighborsHeuristic(q []float64, candidates []int, M int,
 		if len(results) > 0 {
 			// Check relationship with existing results
 			for _, resultID := range results {
-				resultDist := h.distFunc(h.nodes[resultID].GetVector(), h.nodes[nodeID].GetVector())
+				resultDist := h.distanceTo(h.nodeQuery(resultID), nodeID)
 				if resultDist < dist {
 					shouldAdd = false
 					break
@@ -85,6 +85,6 @@ func (h *HNSW) selectNeighborsHeuristic(q []float64, candidates []int, M int,
 // SelectNeighborsHeuristic is the public interface for neighbor selection
 func (h *HNSW) SelectNeighborsHeuristic(q []float64, candidates []int, M int,
 	level int, extendCandidates bool, keepPrunedConnections bool) []int {
-	return h.selectNeighborsHeuristic(q, candidates, M, level,
+	return h.selectNeighborsHeuristic(denseQuery(q), candidates, M, level,
 		extendCandidates, keepPrunedConnections)
 }
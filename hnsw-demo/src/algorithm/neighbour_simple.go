@@ -4,7 +4,7 @@ import (
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/heap"
 )
 
-func (h *HNSW) selectNeighborsSimple(q []float64, candidates []int, M int) []int {
+func (h *HNSW) selectNeighborsSimple(q query, candidates []int, M int) []int {
 	if len(candidates) <= M {
 		return candidates
 	}
@@ -12,7 +12,7 @@ func (h *HNSW) selectNeighborsSimple(q []float64, candidates []int, M int) []int
 	pq := heap.NewPriorityQueue()
 
 	for _, candidateID := range candidates {
-		dist := h.distFunc(q, h.nodes[candidateID].GetVector())
+		dist := h.distanceTo(q, candidateID)
 		pq.PushItem(candidateID, dist)
 	}
 
@@ -28,5 +28,5 @@ func (h *HNSW) selectNeighborsSimple(q []float64, candidates []int, M int) []int
 }
 
 func (h *HNSW) SelectNeighborsSimple(q []float64, candidates []int, M int) []int {
-	return h.selectNeighborsSimple(q, candidates, M)
+	return h.selectNeighborsSimple(denseQuery(q), candidates, M)
 }
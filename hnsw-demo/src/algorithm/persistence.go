@@ -0,0 +1,78 @@
+package algorithm
+
+import (
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+)
+
+// Save writes the index to filename together with its config and metric
+// name, in the storage format implied by the extension of filename
+func (h *HNSW) Save(filename string, description string) error {
+	return h.SaveAs(filename, description, storage.FormatFromFilename(filename))
+}
+
+// SaveAs is Save with an explicit storage format
+func (h *HNSW) SaveAs(filename string, description string, format storage.Format) error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	h.nodesMutex.RLock()
+	defer h.nodesMutex.RUnlock()
+
+	return storage.SaveAs(filename, &storage.SaveData{
+		Metadata: storage.IndexMetadata{
+			MaxLevel:    h.maxLevel,
+			Config:      h.config,
+			Metric:      h.metric.Name,
+			Sparse:      h.IsSparse(),
+			Description: description,
+			Seed:        h.Seed(),
+			DefaultEf:   h.defaultEf,
+		},
+		Nodes:      h.nodes,
+		EntryPoint: h.entryPoint,
+	}, format)
+}
+
+// Load reads an index written by Save. The metric recorded in the file is
+// resolved by name, so custom metrics must be registered before loading.
+// The level generator restarts from the recorded seed
+func Load(filename string) (*HNSW, error) {
+	data, err := storage.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	if data.Metadata.Metric == "" {
+		return nil, fmt.Errorf("index %s does not record a distance metric", filename)
+	}
+
+	newIndex := New
+	if data.Metadata.Sparse {
+		newIndex = NewSparse
+	}
+	h, err := newIndex(data.Metadata.Config, data.Metadata.Metric)
+	if err != nil {
+		return nil, err
+	}
+	// gob decodes an empty node map as nil
+	if data.Nodes != nil {
+		h.nodes = data.Nodes
+	}
+	for _, n := range h.nodes {
+		// gob leaves empty maps nil
+		if n.Neighbors == nil {
+			n.Neighbors = make(map[int][]int)
+		}
+	}
+	h.entryPoint = data.EntryPoint
+	h.maxLevel = data.Metadata.MaxLevel
+	h.defaultEf = data.Metadata.DefaultEf
+	// Files written before seeds were recorded keep the random one
+	if data.Metadata.Seed != 0 {
+		h.setSeed(data.Metadata.Seed)
+	}
+	if n, exists := h.nodes[h.entryPoint]; exists {
+		h.dimension = len(n.Vector)
+	}
+	return h, nil
+}
@@ -0,0 +1,33 @@
+package algorithm
+
+import "github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+
+// query is a vector the graph is searched with: dense in regular indexes,
+// sparse in indexes created by NewSparse
+type query struct {
+	dense  []float64
+	sparse sparse.Vector
+}
+
+// denseQuery wraps a dense vector as a query
+func denseQuery(v []float64) query {
+	return query{dense: v}
+}
+
+// distanceTo returns the internal distance between q and the vector of id
+func (h *HNSW) distanceTo(q query, id int) float64 {
+	n := h.nodes[id]
+	if h.sparseDist != nil {
+		return h.sparseDist(q.sparse, n.GetSparse())
+	}
+	return h.distFunc(q.dense, n.GetVector())
+}
+
+// nodeQuery returns the stored vector of id as a query
+func (h *HNSW) nodeQuery(id int) query {
+	n := h.nodes[id]
+	if h.sparseDist != nil {
+		return query{sparse: n.GetSparse()}
+	}
+	return denseQuery(n.GetVector())
+}
@@ -0,0 +1,79 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+)
+
+var (
+	// ErrNodeNotFound is returned when an id is not present in the index
+	ErrNodeNotFound = errors.New("node not found")
+
+	// ErrNodeExists is returned when inserting an id that is already present
+	ErrNodeExists = errors.New("node already exists")
+)
+
+// Record is a stored element of the index. Sparse indexes set Sparse
+// instead of Vector
+type Record struct {
+	ID         int
+	Vector     []float64
+	Sparse     sparse.Vector
+	Attributes map[string]string
+}
+
+// Get returns a copy of the stored vector and attributes of id
+func (h *HNSW) Get(id int) (Record, error) {
+	h.nodesMutex.RLock()
+	n, exists := h.nodes[id]
+	h.nodesMutex.RUnlock()
+
+	if !exists {
+		return Record{}, fmt.Errorf("%w: %d", ErrNodeNotFound, id)
+	}
+
+	record := Record{ID: id, Attributes: n.GetAttributes()}
+	if h.IsSparse() {
+		record.Sparse = n.GetSparse()
+	} else {
+		record.Vector = n.GetInsertedVector()
+	}
+	return record, nil
+}
+
+// Len returns the number of elements in the index
+func (h *HNSW) Len() int {
+	h.nodesMutex.RLock()
+	defer h.nodesMutex.RUnlock()
+	return len(h.nodes)
+}
+
+// GetBatch returns the records of ids in the same order, failing on the
+// first id that is not present
+func (h *HNSW) GetBatch(ids []int) ([]Record, error) {
+	records := make([]Record, 0, len(ids))
+	for _, id := range ids {
+		record, err := h.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// SetAttributes replaces the attributes attached to id
+func (h *HNSW) SetAttributes(id int, attrs map[string]string) error {
+	h.nodesMutex.RLock()
+	n, exists := h.nodes[id]
+	h.nodesMutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrNodeNotFound, id)
+	}
+
+	n.SetAttributes(attrs)
+	return nil
+}
@@ -0,0 +1,305 @@
+package algorithm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxRepairPasses bounds how often Repair rescans the layers. A pass can
+// leave a node behind when none of its candidates can take another edge;
+// the nodes linked meanwhile give the next pass more to choose from
+const maxRepairPasses = 3
+
+// RepairedNode is a node that Repair linked back into a layer
+type RepairedNode struct {
+	NodeID int
+	Level  int
+
+	// Nodes that link to NodeID on Level after the repair
+	InEdges []int
+}
+
+// RepairReport describes what Repair changed
+type RepairReport struct {
+	// Whether the entry point was replaced because it was missing or not on
+	// the top level
+	EntryPointMoved bool
+
+	// Edges dropped because they were self-loops, duplicates, pointed at
+	// missing nodes or at nodes that do not reach their level
+	RemovedEdges int
+
+	// Node-layer pairs unreachable from the entry point before the repair
+	Unreachable int
+
+	// The pairs that were linked back, in the order they were fixed
+	Reconnected []RepairedNode
+
+	// Node-layer pairs still unreachable afterwards
+	Remaining int
+}
+
+// String summarizes the report
+func (r RepairReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d unreachable, %d reconnected, %d remaining, %d invalid edges removed",
+		r.Unreachable, len(r.Reconnected), r.Remaining, r.RemovedEdges)
+	if r.EntryPointMoved {
+		b.WriteString(", entry point moved")
+	}
+	return b.String()
+}
+
+// Repair fixes the graph so that every node can be reached from the entry
+// point on each of its layers. Invalid edges are dropped first; then each
+// unreachable node is linked the way Insert links a new one: its layer is
+// searched from the entry point and the heuristic selection picks the
+// neighbors it gets edges to and from. Shrinking a full neighbor list never
+// drops an edge that keeps another node reachable. Repair blocks all other
+// operations
+func (h *HNSW) Repair() RepairReport {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.nodesMutex.Lock()
+	defer h.nodesMutex.Unlock()
+
+	var report RepairReport
+	if len(h.nodes) == 0 {
+		return report
+	}
+
+	report.RemovedEdges = h.removeInvalidEdges()
+	if h.entryPointInvalid() {
+		h.replaceEntryPoint()
+		report.EntryPointMoved = true
+	}
+
+	ids := h.sortedIDs()
+	report.Unreachable = h.countUnreachable(ids)
+	report.Remaining = report.Unreachable
+	for pass := 0; pass < maxRepairPasses && report.Remaining > 0; pass++ {
+		for level := h.maxLevel; level >= 0; level-- {
+			// Edges of the search tree over the reachable nodes are never
+			// dropped, so nodes stay reachable while others are linked
+			reached := make(map[int]bool)
+			parent := map[int]int{h.entryPoint: -1}
+			h.extendReachable(reached, h.entryPoint, level, parent)
+
+			for _, id := range ids {
+				if h.nodes[id].GetLevel() < level || reached[id] {
+					continue
+				}
+				if inEdges := h.relink(id, level, reached, parent); len(inEdges) > 0 {
+					report.Reconnected = append(report.Reconnected, RepairedNode{NodeID: id, Level: level, InEdges: inEdges})
+					h.extendReachable(reached, id, level, parent)
+				}
+			}
+		}
+		report.Remaining = h.countUnreachable(ids)
+	}
+	return report
+}
+
+// countUnreachable returns the number of node-layer pairs that cannot be
+// reached from the entry point. The caller must hold both locks
+func (h *HNSW) countUnreachable(ids []int) int {
+	count := 0
+	for level := 0; level <= h.maxLevel; level++ {
+		reached := h.reachable(level)
+		for _, id := range ids {
+			if h.nodes[id].GetLevel() >= level && !reached[id] {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// removeInvalidEdges drops self-loops, duplicates and edges to nodes that
+// are missing or below the level of the edge, returning how many it
+// dropped. The caller must hold both locks
+func (h *HNSW) removeInvalidEdges() int {
+	removed := 0
+	for id, n := range h.nodes {
+		for level := 0; level <= n.GetLevel(); level++ {
+			neighbors, _ := n.GetNeighbors(level)
+			kept := make([]int, 0, len(neighbors))
+			seen := make(map[int]bool, len(neighbors))
+			for _, neighborID := range neighbors {
+				neighbor, exists := h.nodes[neighborID]
+				if neighborID == id || !exists || neighbor.GetLevel() < level || seen[neighborID] {
+					removed++
+					continue
+				}
+				seen[neighborID] = true
+				kept = append(kept, neighborID)
+			}
+			if len(kept) != len(neighbors) {
+				n.SetNeighbors(level, kept)
+			}
+		}
+	}
+	return removed
+}
+
+// entryPointInvalid reports whether the entry point is missing or not the
+// highest node. The caller must hold both locks
+func (h *HNSW) entryPointInvalid() bool {
+	ep, exists := h.nodes[h.entryPoint]
+	if !exists || ep.GetLevel() != h.maxLevel {
+		return true
+	}
+	for _, n := range h.nodes {
+		if n.GetLevel() > h.maxLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// relink connects id on level to the nearest reachable nodes, returning the
+// nodes that link to it afterwards. parent maps each reached node to the
+// node it was reached from; those edges are kept, and the first in-edge of
+// id is added to them. The caller must hold both locks
+func (h *HNSW) relink(id, level int, reached map[int]bool, parent map[int]int) []int {
+	q := h.nodeQuery(id)
+
+	// The descent through the upper layers may end on a node that is not
+	// reachable on this one, and so would be its search results
+	ep := h.greedyClosest(q, level)
+	if !reached[ep] {
+		ep = h.entryPoint
+	}
+
+	var candidates []int
+	for _, candidateID := range h.searchLayer(q, ep, h.config.EfConstruction, level) {
+		if candidateID != id && reached[candidateID] {
+			candidates = append(candidates, candidateID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var inEdges []int
+	for _, neighborID := range h.selectNeighborsHeuristic(q, candidates, h.config.M, level, true, true) {
+		if neighborID == id {
+			continue
+		}
+		h.linkSafely(id, neighborID, level, parent)
+		if h.linkSafely(neighborID, id, level, parent) {
+			inEdges = append(inEdges, neighborID)
+			if _, exists := parent[id]; !exists {
+				parent[id] = neighborID
+			}
+		}
+	}
+	if len(inEdges) > 0 {
+		return inEdges
+	}
+
+	// Every list id was offered to prefers the edges it has. Use the
+	// nearest candidate with room for another edge, or replace the
+	// furthest neighbor it can spare
+	for _, candidateID := range candidates {
+		c := h.nodes[candidateID]
+		neighbors, _ := c.GetNeighbors(level)
+		if len(neighbors) < h.maxDegree(level) {
+			c.AddNeighbor(level, id)
+			parent[id] = candidateID
+			return []int{candidateID}
+		}
+
+		cq := h.nodeQuery(candidateID)
+		victim, victimDist := -1, 0.0
+		for i, neighborID := range neighbors {
+			if p, exists := parent[neighborID]; exists && p == candidateID {
+				continue
+			}
+			if dist := h.distanceTo(cq, neighborID); victim < 0 || dist > victimDist {
+				victim, victimDist = i, dist
+			}
+		}
+		if victim >= 0 {
+			neighbors[victim] = id
+			c.SetNeighbors(level, neighbors)
+			parent[id] = candidateID
+			return []int{candidateID}
+		}
+	}
+	return nil
+}
+
+// linkSafely adds the edge from -> to on level like addEdge, unless
+// shrinking the neighbors of from would drop to or an edge recorded in
+// parent. It reports whether the edge was added
+func (h *HNSW) linkSafely(from, to, level int, parent map[int]int) bool {
+	n := h.nodes[from]
+	neighbors, _ := n.GetNeighbors(level)
+	if slices.Contains(neighbors, to) {
+		return true
+	}
+
+	updated := append(slices.Clone(neighbors), to)
+	if maxM := h.maxDegree(level); len(updated) > maxM {
+		updated = h.selectNeighborsHeuristic(h.nodeQuery(from), updated, maxM, level, false, true)
+		if !slices.Contains(updated, to) {
+			return false
+		}
+		for _, neighborID := range neighbors {
+			if p, exists := parent[neighborID]; exists && p == from && !slices.Contains(updated, neighborID) {
+				return false
+			}
+		}
+	}
+	n.SetNeighbors(level, updated)
+	return true
+}
+
+// greedyClosest descends from the entry point to the layer above level,
+// moving to the closest neighbor of q on each layer, and returns where it
+// stopped. The caller must hold both locks
+func (h *HNSW) greedyClosest(q query, level int) int {
+	curr := h.entryPoint
+	currDist := h.distanceTo(q, curr)
+	for lc := h.maxLevel; lc > level; lc-- {
+		for changed := true; changed; {
+			changed = false
+			neighbors, _ := h.nodes[curr].GetNeighbors(lc)
+			for _, neighborID := range neighbors {
+				if dist := h.distanceTo(q, neighborID); dist < currDist {
+					curr, currDist = neighborID, dist
+					changed = true
+				}
+			}
+		}
+	}
+	return curr
+}
+
+// extendReachable adds the nodes reachable from id on level to reached and,
+// if parent is not nil, records the node each of them was reached from.
+// The caller must hold both locks
+func (h *HNSW) extendReachable(reached map[int]bool, id, level int, parent map[int]int) {
+	if reached[id] {
+		return
+	}
+	reached[id] = true
+	queue := []int{id}
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+
+		neighbors, _ := h.nodes[curr].GetNeighbors(level)
+		for _, neighborID := range neighbors {
+			if _, exists := h.nodes[neighborID]; exists && !reached[neighborID] {
+				reached[neighborID] = true
+				if parent != nil {
+					parent[neighborID] = curr
+				}
+				queue = append(queue, neighborID)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+)
+
+// ErrDimensionMismatch is returned when a vector's dimension differs from
+// the dimension of the index
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// SearchOptions controls a single query
+type SearchOptions struct {
+	// Number of nearest neighbors to return
+	K int
+
+	// Size of the dynamic candidate list on layer 0, defaults to the
+	// index's DefaultEf, or 2*K if it has none, and is never smaller than K
+	Ef int
+
+	// Only nodes for which Filter returns true are returned, nil accepts all
+	Filter func(id int) bool
+
+	// Whether to copy the stored vector into each Result
+	IncludeVectors bool
+
+	// Whether to copy the attached attributes into each Result
+	IncludeAttributes bool
+
+	// Results further than MaxDistance are dropped, 0 means no threshold
+	MaxDistance float64
+
+	// Maximum number of distance evaluations, 0 means unlimited
+	MaxDistanceComputations int
+
+	// Maximum number of visited nodes, 0 means unlimited
+	MaxVisitedNodes int
+}
+
+// SearchStats reports the work done by a single query
+type SearchStats struct {
+	// Number of distance evaluations against stored vectors
+	DistanceComputations int
+
+	// Number of distinct nodes visited across all layers
+	VisitedNodes int
+
+	// Number of candidates whose neighborhoods were expanded
+	Hops int
+
+	// Whether a budget in SearchOptions stopped the search early
+	Truncated bool
+}
+
+// Result is a single search hit
+type Result struct {
+	ID       int
+	Distance float64
+
+	// Stored vector, only set when SearchOptions.IncludeVectors is true.
+	// Sparse indexes set Sparse instead
+	Vector []float64
+	Sparse sparse.Vector
+
+	// Attached attributes, only set when SearchOptions.IncludeAttributes is true
+	Attributes map[string]string
+}
+
+// searchState carries the context, budget and counters of one query
+// through the layers it searches
+type searchState struct {
+	ctx   context.Context
+	opts  SearchOptions
+	stats SearchStats
+}
+
+// newSearchState creates the state for a query bounded by ctx and opts
+func newSearchState(ctx context.Context, opts SearchOptions) *searchState {
+	return &searchState{ctx: ctx, opts: opts}
+}
+
+// countDistance counts a distance evaluation
+func (s *searchState) countDistance() {
+	s.stats.DistanceComputations++
+}
+
+// filtering reports whether results on level are restricted by a filter
+func (s *searchState) filtering(level int) bool {
+	return level == 0 && s.opts.Filter != nil
+}
+
+// accepts reports whether id may be returned as a result on level
+func (s *searchState) accepts(level int, id int) bool {
+	return !s.filtering(level) || s.opts.Filter(id)
+}
+
+// visit counts a newly visited node
+func (s *searchState) visit() {
+	s.stats.VisitedNodes++
+}
+
+// exhausted reports whether the query has used up its budget, marking the
+// search as truncated if so
+func (s *searchState) exhausted() bool {
+	if (s.opts.MaxDistanceComputations > 0 && s.stats.DistanceComputations >= s.opts.MaxDistanceComputations) ||
+		(s.opts.MaxVisitedNodes > 0 && s.stats.VisitedNodes >= s.opts.MaxVisitedNodes) {
+		s.stats.Truncated = true
+	}
+	return s.stats.Truncated
+}
+
+// KNNSearchWithOptions returns the K nearest neighbors of q with their
+// distances, together with the work the query used. The search stops early
+// once a budget in opts is spent, returning the best results found so far.
+// If ctx is done first, the partial results are returned with ctx.Err()
+func (h *HNSW) KNNSearchWithOptions(ctx context.Context, q []float64, K int, opts SearchOptions) ([]int, []float64, SearchStats, error) {
+	opts.K = K
+	results, stats, err := h.SearchWithStats(ctx, q, opts)
+	ids, distances := splitResults(results)
+	return ids, distances, stats, err
+}
+
+// splitResults separates results into parallel id and distance slices
+func splitResults(results []Result) ([]int, []float64) {
+	ids := make([]int, len(results))
+	distances := make([]float64, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+		distances[i] = result.Distance
+	}
+	return ids, distances
+}
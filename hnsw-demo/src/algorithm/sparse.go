@@ -0,0 +1,95 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/node"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+)
+
+var (
+	// ErrSparseIndex is returned when dense vectors are used with an index
+	// created by NewSparse
+	ErrSparseIndex = errors.New("index stores sparse vectors")
+
+	// ErrDenseIndex is returned when sparse vectors are used with an index
+	// created by New
+	ErrDenseIndex = errors.New("index stores dense vectors")
+)
+
+// NewSparse creates a new HNSW index over sparse vectors. The metric must
+// be distance.DotProduct or distance.Cosine
+func NewSparse(cfg config.Config, metric string) (*HNSW, error) {
+	sparseDist, err := sparse.GetDistanceFunction(metric)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distance function: %v", err)
+	}
+
+	h, err := New(cfg, metric)
+	if err != nil {
+		return nil, err
+	}
+	h.sparseDist = sparseDist
+	return h, nil
+}
+
+// IsSparse reports whether the index stores sparse vectors
+func (h *HNSW) IsSparse() bool {
+	return h.sparseDist != nil
+}
+
+// InsertSparse adds a new sparse element to a sparse index
+func (h *HNSW) InsertSparse(id int, vector sparse.Vector) error {
+	return h.InsertSparseWithAttributes(id, vector, nil)
+}
+
+// InsertSparseWithAttributes adds a new sparse element with an attached
+// payload to a sparse index
+func (h *HNSW) InsertSparseWithAttributes(id int, vector sparse.Vector, attrs map[string]string) error {
+	if !h.IsSparse() {
+		return ErrDenseIndex
+	}
+	if err := vector.Validate(); err != nil {
+		return fmt.Errorf("invalid sparse vector: %v", err)
+	}
+
+	// Check if node already exists
+	h.nodesMutex.Lock()
+	if _, exists := h.nodes[id]; exists {
+		h.nodesMutex.Unlock()
+		return fmt.Errorf("%w: %d", ErrNodeExists, id)
+	}
+
+	// Create new node
+	vector = vector.Clone()
+	level := h.generateLevel()
+	newNode := node.NewSparseNode(id, vector, level)
+	newNode.SetAttributes(attrs)
+	h.nodes[id] = newNode
+	h.nodesMutex.Unlock()
+
+	h.link(newNode, query{sparse: vector})
+	return nil
+}
+
+// SearchSparse is Search for sparse indexes. Result.Sparse holds the stored
+// vector when opts.IncludeVectors is set
+func (h *HNSW) SearchSparse(ctx context.Context, q sparse.Vector, opts SearchOptions) ([]Result, error) {
+	results, _, err := h.SearchSparseWithStats(ctx, q, opts)
+	return results, err
+}
+
+// SearchSparseWithStats is SearchSparse that also reports the work the
+// query used
+func (h *HNSW) SearchSparseWithStats(ctx context.Context, q sparse.Vector, opts SearchOptions) ([]Result, SearchStats, error) {
+	if !h.IsSparse() {
+		return []Result{}, SearchStats{}, ErrDenseIndex
+	}
+	if err := q.Validate(); err != nil {
+		return []Result{}, SearchStats{}, fmt.Errorf("invalid sparse vector: %v", err)
+	}
+	return h.search(ctx, query{sparse: q}, opts)
+}
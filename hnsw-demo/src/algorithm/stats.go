@@ -0,0 +1,114 @@
+package algorithm
+
+import (
+	"unsafe"
+)
+
+// LevelStats describes one layer of the graph
+type LevelStats struct {
+	Level int
+
+	// Nodes whose level is at least Level
+	Nodes int
+
+	// Degree distribution of those nodes on this layer
+	MinDegree  int
+	MeanDegree float64
+	MaxDegree  int
+	Histogram  map[int]int // degree -> number of nodes
+
+	// Nodes of this layer that cannot be reached from the entry point
+	Unreachable int
+}
+
+// Stats describes the size and health of the index
+type Stats struct {
+	Nodes int
+
+	// Elements removed by Delete since the index was created or loaded.
+	// Deleted nodes are dropped from the graph at once, so there are no
+	// tombstones left to count; a high ratio of deletions to Nodes suggests
+	// a rebuild
+	Deleted int
+
+	EntryPoint int
+	MaxLevel   int
+
+	// Per-layer statistics, indexed by level
+	Levels []LevelStats
+
+	// Nodes unreachable from the entry point on at least one of their layers
+	Unreachable int
+
+	// Rough number of bytes held by vectors, neighbor lists, attributes and
+	// the node structures
+	MemoryBytes int64
+}
+
+// Stats computes statistics of the index. It walks the whole graph, so it
+// costs about as much as Verify
+func (h *HNSW) Stats() Stats {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	h.nodesMutex.RLock()
+	defer h.nodesMutex.RUnlock()
+
+	stats := Stats{
+		Nodes:      len(h.nodes),
+		Deleted:    h.deleted,
+		EntryPoint: h.entryPoint,
+		MaxLevel:   h.maxLevel,
+	}
+	if len(h.nodes) == 0 {
+		return stats
+	}
+
+	stats.Levels = make([]LevelStats, h.maxLevel+1)
+	for level := range stats.Levels {
+		stats.Levels[level] = LevelStats{Level: level, MinDegree: -1, Histogram: make(map[int]int)}
+	}
+
+	for _, n := range h.nodes {
+		// The node plus its entry in the node map
+		stats.MemoryBytes += n.MemoryUsage() + int64(unsafe.Sizeof(n)+unsafe.Sizeof(0))
+
+		all := n.GetAllNeighbors()
+		for level := 0; level <= min(n.GetLevel(), h.maxLevel); level++ {
+			ls := &stats.Levels[level]
+			degree := len(all[level])
+			ls.Nodes++
+			ls.Histogram[degree]++
+			ls.MeanDegree += float64(degree)
+			if ls.MinDegree < 0 || degree < ls.MinDegree {
+				ls.MinDegree = degree
+			}
+			if degree > ls.MaxDegree {
+				ls.MaxDegree = degree
+			}
+		}
+	}
+	for level := range stats.Levels {
+		ls := &stats.Levels[level]
+		if ls.Nodes > 0 {
+			ls.MeanDegree /= float64(ls.Nodes)
+		} else {
+			ls.MinDegree = 0
+		}
+	}
+
+	if _, exists := h.nodes[h.entryPoint]; !exists {
+		return stats
+	}
+	unreachable := make(map[int]bool)
+	for level := range stats.Levels {
+		reached := h.reachable(level)
+		for id, n := range h.nodes {
+			if n.GetLevel() >= level && !reached[id] {
+				stats.Levels[level].Unreachable++
+				unreachable[id] = true
+			}
+		}
+	}
+	stats.Unreachable = len(unreachable)
+	return stats
+}
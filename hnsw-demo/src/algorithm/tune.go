@@ -0,0 +1,208 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrRecallUnreachable is returned by TuneEf when no ef reaches the target
+// recall on the sample
+var ErrRecallUnreachable = errors.New("target recall unreachable")
+
+// EfTrial is the recall measured at one ef
+type EfTrial struct {
+	Ef     int
+	Recall float64
+}
+
+// EfTuning is the result of TuneEf
+type EfTuning struct {
+	// Smallest ef found to reach the target recall
+	Ef int
+
+	// Recall of the sample at Ef
+	Recall float64
+
+	// Every ef tried, in the order it was tried
+	Trials []EfTrial
+}
+
+// DefaultEf returns the ef used by searches that do not set one, 0 if they
+// use 2*K
+func (h *HNSW) DefaultEf() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.defaultEf
+}
+
+// SetDefaultEf sets the ef used by searches that do not set one. Zero
+// restores the 2*K default. The value is saved with the index
+func (h *HNSW) SetDefaultEf(ef int) error {
+	if ef < 0 {
+		return fmt.Errorf("ef must not be negative, got %d", ef)
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.defaultEf = ef
+	return nil
+}
+
+// TuneEf finds the smallest ef whose K-nearest-neighbor searches reach
+// targetRecall on the sample queries and makes it the index's DefaultEf.
+// The true neighbors of each query are computed by brute force, then ef
+// is doubled from K until the target is met and narrowed down by binary
+// search. If even an ef as large as the index misses the target, the
+// default is left unchanged and ErrRecallUnreachable is returned with the
+// trials made
+func (h *HNSW) TuneEf(ctx context.Context, queries [][]float64, K int, targetRecall float64) (EfTuning, error) {
+	if h.IsSparse() {
+		return EfTuning{}, ErrSparseIndex
+	}
+	if len(queries) == 0 {
+		return EfTuning{}, fmt.Errorf("no sample queries")
+	}
+	if K <= 0 {
+		return EfTuning{}, fmt.Errorf("K must be positive, got %d", K)
+	}
+	if targetRecall <= 0 || targetRecall > 1 {
+		return EfTuning{}, fmt.Errorf("target recall must be in (0, 1], got %g", targetRecall)
+	}
+
+	truth, err := h.GroundTruth(ctx, queries, K)
+	if err != nil {
+		return EfTuning{}, err
+	}
+
+	var tuning EfTuning
+	measure := func(ef int) (float64, error) {
+		recall, _, err := h.SampleRecall(ctx, queries, truth, SearchOptions{K: K, Ef: ef})
+		if err == nil {
+			tuning.Trials = append(tuning.Trials, EfTrial{Ef: ef, Recall: recall})
+		}
+		return recall, err
+	}
+
+	// Double ef until the target is met; no ef beyond the index size helps
+	limit := max(h.Len(), K)
+	lo, hi := K-1, K
+	recall, err := measure(hi)
+	for err == nil && recall < targetRecall && hi < limit {
+		lo, hi = hi, min(2*hi, limit)
+		recall, err = measure(hi)
+	}
+	if err != nil {
+		return tuning, err
+	}
+	if recall < targetRecall {
+		return tuning, fmt.Errorf("%w: recall %.4f at ef %d, target %.4f", ErrRecallUnreachable, recall, hi, targetRecall)
+	}
+
+	// The smallest passing ef lies in (lo, hi]
+	best := recall
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		r, err := measure(mid)
+		if err != nil {
+			return tuning, err
+		}
+		if r >= targetRecall {
+			hi, best = mid, r
+		} else {
+			lo = mid
+		}
+	}
+
+	tuning.Ef, tuning.Recall = hi, best
+	if err := h.SetDefaultEf(hi); err != nil {
+		return tuning, err
+	}
+	return tuning, nil
+}
+
+// GroundTruth returns the ids of the K nearest elements of each query,
+// found by ExactSearch
+func (h *HNSW) GroundTruth(ctx context.Context, queries [][]float64, K int) ([]map[int]bool, error) {
+	truth := make([]map[int]bool, len(queries))
+	for i, q := range queries {
+		results, err := h.ExactSearch(ctx, q, K)
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		truth[i] = make(map[int]bool, len(results))
+		for _, result := range results {
+			truth[i][result.ID] = true
+		}
+	}
+	return truth, nil
+}
+
+// ExactSearch returns the K nearest elements of q by comparing it with
+// every element, nearest first and ties broken by id. It is the reference
+// the graph search is measured against
+func (h *HNSW) ExactSearch(ctx context.Context, q []float64, K int) ([]Result, error) {
+	if h.IsSparse() {
+		return []Result{}, ErrSparseIndex
+	}
+	if K <= 0 {
+		return []Result{}, fmt.Errorf("K must be positive, got %d", K)
+	}
+	if err := ctx.Err(); err != nil {
+		return []Result{}, err
+	}
+
+	h.nodesMutex.RLock()
+	defer h.nodesMutex.RUnlock()
+	if len(h.nodes) > 0 && len(q) != h.dimension {
+		return []Result{}, fmt.Errorf("%w: expected %d, got %d", ErrDimensionMismatch, h.dimension, len(q))
+	}
+
+	dq := denseQuery(h.prepareVector(q))
+	all := make([]Result, 0, len(h.nodes))
+	for id := range h.nodes {
+		all = append(all, Result{ID: id, Distance: h.distanceTo(dq, id)})
+	}
+	sort.Slice(all, func(a, b int) bool {
+		if all[a].Distance != all[b].Distance {
+			return all[a].Distance < all[b].Distance
+		}
+		return all[a].ID < all[b].ID
+	})
+
+	results := all[:min(K, len(all))]
+	for i := range results {
+		results[i].Distance = h.metric.FinalizeDistance(results[i].Distance)
+	}
+	return results, nil
+}
+
+// SampleRecall searches each query with opts and returns the fraction of
+// the true neighbors in truth, as returned by GroundTruth, that were found
+// and the total time spent searching
+func (h *HNSW) SampleRecall(ctx context.Context, queries [][]float64, truth []map[int]bool, opts SearchOptions) (float64, time.Duration, error) {
+	if len(truth) != len(queries) {
+		return 0, 0, fmt.Errorf("got true neighbors for %d of %d queries", len(truth), len(queries))
+	}
+	found, total := 0, 0
+	var elapsed time.Duration
+	for i, q := range queries {
+		began := time.Now()
+		results, err := h.Search(ctx, q, opts)
+		elapsed += time.Since(began)
+		if err != nil {
+			return 0, elapsed, fmt.Errorf("query %d: %w", i, err)
+		}
+		for _, result := range results {
+			if truth[i][result.ID] {
+				found++
+			}
+		}
+		total += len(truth[i])
+	}
+	if total == 0 {
+		return 1, elapsed, nil
+	}
+	return float64(found) / float64(total), elapsed, nil
+}
@@ -0,0 +1,221 @@
+package algorithm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProblemKind classifies a structural problem found by Verify
+type ProblemKind string
+
+const (
+	// A neighbor id does not exist in the index
+	ProblemMissingNeighbor ProblemKind = "missing-neighbor"
+
+	// A node has neighbors on a level above its own, or a neighbor does not
+	// reach the level it is linked on
+	ProblemLevelMismatch ProblemKind = "level-mismatch"
+
+	// A node lists itself as a neighbor
+	ProblemSelfLoop ProblemKind = "self-loop"
+
+	// A node lists the same neighbor twice on one level
+	ProblemDuplicateNeighbor ProblemKind = "duplicate-neighbor"
+
+	// A node has more neighbors than MaxM on layer 0 or M above it
+	ProblemDegreeExceeded ProblemKind = "degree-exceeded"
+
+	// The entry point is missing or does not sit at the top level
+	ProblemEntryPoint ProblemKind = "entry-point"
+
+	// A node cannot be reached from the entry point on a layer it belongs to
+	ProblemUnreachable ProblemKind = "unreachable"
+)
+
+// Problem is a single structural problem of the graph
+type Problem struct {
+	Kind   ProblemKind
+	NodeID int
+	Level  int
+
+	// The neighbor involved, if any
+	NeighborID int
+
+	Message string
+}
+
+// VerifyReport is the result of Verify
+type VerifyReport struct {
+	Nodes      int
+	MaxLevel   int
+	EntryPoint int
+
+	// Number of edges checked across all levels
+	Edges int
+
+	// Problems, ordered by node id, level and kind
+	Problems []Problem
+}
+
+// OK reports whether no problems were found
+func (r VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Count returns the number of problems of the given kind
+func (r VerifyReport) Count(kind ProblemKind) int {
+	count := 0
+	for _, p := range r.Problems {
+		if p.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// String summarizes the report, one problem per line
+func (r VerifyReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d nodes, %d edges, max level %d, entry point %d: ", r.Nodes, r.Edges, r.MaxLevel, r.EntryPoint)
+	if r.OK() {
+		b.WriteString("OK")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d problems", len(r.Problems))
+	for _, p := range r.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s", p.Kind, p.Message)
+	}
+	return b.String()
+}
+
+// Verify checks the structure of the graph: every neighbor exists and
+// reaches the level it is linked on, no node links itself or the same
+// neighbor twice, degrees respect MaxM and M, the entry point sits at
+// maxLevel, and every layer is reachable from the entry point.
+// Unreachable nodes do not need deletes: when addEdge prunes a neighbor
+// list it can drop the only in-edge of a node, so a few graphs built by
+// inserts alone report ProblemUnreachable until Repair reconnects them
+func (h *HNSW) Verify() VerifyReport {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	h.nodesMutex.RLock()
+	defer h.nodesMutex.RUnlock()
+
+	report := VerifyReport{Nodes: len(h.nodes), MaxLevel: h.maxLevel, EntryPoint: h.entryPoint}
+	if len(h.nodes) == 0 {
+		return report
+	}
+
+	add := func(kind ProblemKind, nodeID, level, neighborID int, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, Problem{
+			Kind:       kind,
+			NodeID:     nodeID,
+			Level:      level,
+			NeighborID: neighborID,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Entry point
+	topLevel := 0
+	for _, n := range h.nodes {
+		if level := n.GetLevel(); level > topLevel {
+			topLevel = level
+		}
+	}
+	if ep, exists := h.nodes[h.entryPoint]; !exists {
+		add(ProblemEntryPoint, h.entryPoint, h.maxLevel, -1, "entry point %d does not exist", h.entryPoint)
+	} else if level := ep.GetLevel(); level != h.maxLevel || level != topLevel {
+		add(ProblemEntryPoint, h.entryPoint, level, -1,
+			"entry point %d has level %d, max level is %d and the highest node level is %d",
+			h.entryPoint, level, h.maxLevel, topLevel)
+	}
+
+	ids := h.sortedIDs()
+	for _, id := range ids {
+		n := h.nodes[id]
+		nodeLevel := n.GetLevel()
+		all := n.GetAllNeighbors()
+
+		levels := make([]int, 0, len(all))
+		for level := range all {
+			levels = append(levels, level)
+		}
+		sort.Ints(levels)
+
+		for _, level := range levels {
+			neighbors := all[level]
+			if level < 0 || level > nodeLevel {
+				if len(neighbors) > 0 {
+					add(ProblemLevelMismatch, id, level, -1, "node %d has neighbors on level %d above its level %d", id, level, nodeLevel)
+				}
+				continue
+			}
+			if maxM := h.maxDegree(level); len(neighbors) > maxM {
+				add(ProblemDegreeExceeded, id, level, -1, "node %d has %d neighbors on level %d, limit %d", id, len(neighbors), level, maxM)
+			}
+
+			seen := make(map[int]bool, len(neighbors))
+			for _, neighborID := range neighbors {
+				report.Edges++
+				neighbor, exists := h.nodes[neighborID]
+				switch {
+				case neighborID == id:
+					add(ProblemSelfLoop, id, level, neighborID, "node %d links itself on level %d", id, level)
+				case !exists:
+					add(ProblemMissingNeighbor, id, level, neighborID, "node %d links missing node %d on level %d", id, neighborID, level)
+				case neighbor.GetLevel() < level:
+					add(ProblemLevelMismatch, id, level, neighborID, "node %d links node %d on level %d, which only reaches level %d",
+						id, neighborID, level, neighbor.GetLevel())
+				case seen[neighborID]:
+					add(ProblemDuplicateNeighbor, id, level, neighborID, "node %d links node %d twice on level %d", id, neighborID, level)
+				}
+				seen[neighborID] = true
+			}
+		}
+	}
+
+	// Reachability of each layer from the entry point
+	if _, exists := h.nodes[h.entryPoint]; exists {
+		for level := 0; level <= h.maxLevel; level++ {
+			reached := h.reachable(level)
+			for _, id := range ids {
+				if h.nodes[id].GetLevel() >= level && !reached[id] {
+					add(ProblemUnreachable, id, level, -1, "node %d is unreachable from the entry point on level %d", id, level)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		a, b := report.Problems[i], report.Problems[j]
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		return a.Kind < b.Kind
+	})
+	return report
+}
+
+// sortedIDs returns the ids of all nodes in increasing order. The caller
+// must hold nodesMutex
+func (h *HNSW) sortedIDs() []int {
+	ids := make([]int, 0, len(h.nodes))
+	for id := range h.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// reachable returns the nodes reached by following edges on level from the
+// entry point. The caller must hold both locks
+func (h *HNSW) reachable(level int) map[int]bool {
+	reached := make(map[int]bool)
+	h.extendReachable(reached, h.entryPoint, level, nil)
+	return reached
+}
@@ -0,0 +1,173 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// Options describes a collection to create
+type Options struct {
+	Config config.Config
+
+	// Registered name of the distance metric
+	Metric string
+
+	// Dimension of every vector in the collection
+	Dimension int
+
+	Description string
+}
+
+// Info is the manifest entry of a collection
+type Info struct {
+	Name        string        `json:"name"`
+	Metric      string        `json:"metric"`
+	Dimension   int           `json:"dimension"`
+	Config      config.Config `json:"config"`
+	Description string        `json:"description,omitempty"`
+	File        string        `json:"file"` // relative to the data directory
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Collection is a named index with a fixed metric and dimension. Its
+// methods are safe for concurrent use; writes are serialized against
+// searches
+type Collection struct {
+	info  Info
+	mutex sync.RWMutex
+	index *algorithm.HNSW
+}
+
+// Info returns the manifest entry of the collection
+func (c *Collection) Info() Info {
+	return c.info
+}
+
+// Name returns the name of the collection
+func (c *Collection) Name() string {
+	return c.info.Name
+}
+
+// Index returns the underlying index, for operations the collection does
+// not wrap. Calls on it are not synchronized with the collection's methods
+func (c *Collection) Index() *algorithm.HNSW {
+	return c.index
+}
+
+// Len returns the number of elements in the collection
+func (c *Collection) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.Len()
+}
+
+// Stats computes statistics of the collection's graph
+func (c *Collection) Stats() algorithm.Stats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.Stats()
+}
+
+// Insert adds a vector of the collection's dimension
+func (c *Collection) Insert(id int, vector []float64) error {
+	return c.InsertWithAttributes(id, vector, nil)
+}
+
+// InsertWithAttributes adds a vector of the collection's dimension with an
+// attached payload
+func (c *Collection) InsertWithAttributes(id int, vector []float64, attrs map[string]string) error {
+	if err := c.checkDimension(vector); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.index.InsertWithAttributes(id, vector, attrs)
+}
+
+// Upsert inserts a vector, replacing the element if id is already present
+func (c *Collection) Upsert(id int, vector []float64, attrs map[string]string) error {
+	if err := c.checkDimension(vector); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.index.Delete(id); err != nil && !errors.Is(err, algorithm.ErrNodeNotFound) {
+		return err
+	}
+	return c.index.InsertWithAttributes(id, vector, attrs)
+}
+
+// Get returns the stored vector and attributes of id
+func (c *Collection) Get(id int) (algorithm.Record, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.Get(id)
+}
+
+// Search runs a query against the collection
+func (c *Collection) Search(ctx context.Context, q []float64, opts algorithm.SearchOptions) ([]algorithm.Result, error) {
+	if err := c.checkDimension(q); err != nil {
+		return []algorithm.Result{}, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.Search(ctx, q, opts)
+}
+
+// RangeSearch returns all elements within radius of q, nearest first
+func (c *Collection) RangeSearch(ctx context.Context, q []float64, radius float64, ef int) ([]int, []float64, error) {
+	if err := c.checkDimension(q); err != nil {
+		return nil, nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.RangeSearchContext(ctx, q, radius, ef)
+}
+
+// TuneEf finds the smallest ef reaching targetRecall on the sample queries
+// and makes it the default ef of the collection's searches. The default is
+// saved with the index
+func (c *Collection) TuneEf(ctx context.Context, queries [][]float64, K int, targetRecall float64) (algorithm.EfTuning, error) {
+	for _, q := range queries {
+		if err := c.checkDimension(q); err != nil {
+			return algorithm.EfTuning{}, err
+		}
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.TuneEf(ctx, queries, K, targetRecall)
+}
+
+// Delete removes an element from the collection
+func (c *Collection) Delete(id int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.index.Delete(id)
+}
+
+// save writes the index file of the collection
+func (c *Collection) save(filename string) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.index.Save(filename, c.info.Description)
+}
+
+// checkDimension rejects dense vectors whose length differs from the
+// declared dimension, even while the collection is still empty
+func (c *Collection) checkDimension(v []float64) error {
+	if len(v) != c.info.Dimension {
+		return fmt.Errorf("%w: expected %d, got %d", algorithm.ErrDimensionMismatch, c.info.Dimension, len(v))
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// ManifestFile is the name of the manifest inside the data directory
+const ManifestFile = "manifest.json"
+
+var (
+	// ErrCollectionNotFound is returned when no collection has the given name
+	ErrCollectionNotFound = errors.New("collection not found")
+
+	// ErrCollectionExists is returned when creating a collection whose name
+	// is taken
+	ErrCollectionExists = errors.New("collection already exists")
+
+	// ErrInvalidOptions is returned when creating a collection with an
+	// invalid name, metric, dimension or config
+	ErrInvalidOptions = errors.New("invalid collection options")
+)
+
+// validName restricts names to characters that are safe in file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// manifest lists the collections stored in a data directory
+type manifest struct {
+	Collections []Info `json:"collections"`
+}
+
+// Manager owns the collections of one data directory
+type Manager struct {
+	dir         string
+	mutex       sync.RWMutex
+	collections map[string]*Collection
+}
+
+// Open opens the data directory dir, creating it if needed, and loads the
+// collections listed in its manifest. Collections created but never saved
+// are opened empty
+func Open(dir string) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	m := &Manager{dir: dir, collections: make(map[string]*Collection)}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var mf manifest
+	if err := json.Unmarshal(data, &mf); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	for _, info := range mf.Collections {
+		index, err := m.loadIndex(info)
+		if err != nil {
+			return nil, fmt.Errorf("collection %q: %w", info.Name, err)
+		}
+		m.collections[info.Name] = &Collection{info: info, index: index}
+	}
+	return m, nil
+}
+
+// loadIndex loads the index file of info, or creates an empty index if the
+// collection was never saved. Collections only hold dense vectors, so a
+// sparse index file is rejected
+func (m *Manager) loadIndex(info Info) (*algorithm.HNSW, error) {
+	filename := filepath.Join(m.dir, info.File)
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return newIndex(info)
+	}
+	index, err := algorithm.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	if index.IsSparse() {
+		return nil, algorithm.ErrSparseIndex
+	}
+	return index, nil
+}
+
+// newIndex creates an empty index for info
+func newIndex(info Info) (*algorithm.HNSW, error) {
+	return algorithm.New(info.Config, info.Metric)
+}
+
+// Dir returns the data directory
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Create creates a new empty collection and records it in the manifest
+func (m *Manager) Create(name string, opts Options) (*Collection, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("%w: invalid collection name %q", ErrInvalidOptions, name)
+	}
+	if err := opts.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: invalid config: %w", ErrInvalidOptions, err)
+	}
+	if _, err := distance.Lookup(opts.Metric); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	if opts.Dimension <= 0 {
+		return nil, fmt.Errorf("%w: dimension must be positive, got %d", ErrInvalidOptions, opts.Dimension)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.collections[name]; exists {
+		return nil, fmt.Errorf("%w: %q", ErrCollectionExists, name)
+	}
+
+	info := Info{
+		Name:        name,
+		Metric:      opts.Metric,
+		Dimension:   opts.Dimension,
+		Config:      opts.Config,
+		Description: opts.Description,
+		File:        name + ".hnsw",
+		CreatedAt:   time.Now().UTC(),
+	}
+	index, err := newIndex(info)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Collection{info: info, index: index}
+	m.collections[name] = c
+	if err := m.writeManifest(); err != nil {
+		delete(m.collections, name)
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the collection with the given name
+func (m *Manager) Get(name string) (*Collection, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	c, exists := m.collections[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrCollectionNotFound, name)
+	}
+	return c, nil
+}
+
+// List returns the manifest entries of all collections, sorted by name
+func (m *Manager) List() []Info {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	infos := make([]Info, 0, len(m.collections))
+	for _, c := range m.collections {
+		infos = append(infos, c.info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Drop removes a collection from the manifest and deletes its index file
+func (m *Manager) Drop(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	c, exists := m.collections[name]
+	if !exists {
+		return fmt.Errorf("%w: %q", ErrCollectionNotFound, name)
+	}
+
+	delete(m.collections, name)
+	if err := m.writeManifest(); err != nil {
+		m.collections[name] = c
+		return err
+	}
+
+	err := os.Remove(filepath.Join(m.dir, c.info.File))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove index file: %w", err)
+	}
+	return nil
+}
+
+// Save writes the index file of one collection
+func (m *Manager) Save(name string) error {
+	c, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	return c.save(filepath.Join(m.dir, c.info.File))
+}
+
+// SaveAll writes the index files of all collections and the manifest
+func (m *Manager) SaveAll() error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, c := range m.collections {
+		if err := c.save(filepath.Join(m.dir, c.info.File)); err != nil {
+			return fmt.Errorf("collection %q: %w", c.info.Name, err)
+		}
+	}
+	return m.writeManifest()
+}
+
+// writeManifest atomically replaces the manifest with the current
+// collections. The caller must hold the mutex
+func (m *Manager) writeManifest() error {
+	mf := manifest{Collections: make([]Info, 0, len(m.collections))}
+	for _, c := range m.collections {
+		mf.Collections = append(mf.Collections, c.info)
+	}
+	sort.Slice(mf.Collections, func(i, j int) bool {
+		return mf.Collections[i].Name < mf.Collections[j].Name
+	})
+
+	data, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	filename := filepath.Join(m.dir, ManifestFile)
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
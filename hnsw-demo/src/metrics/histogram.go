@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms
+var DefaultBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5,
+}
+
+// counter is a monotonically increasing count
+type counter struct {
+	value atomic.Uint64
+}
+
+func (c *counter) add(n int) {
+	if n > 0 {
+		c.value.Add(uint64(n))
+	}
+}
+
+func (c *counter) get() uint64 {
+	return c.value.Load()
+}
+
+// histogram counts observations into buckets with fixed upper bounds
+type histogram struct {
+	mutex   sync.Mutex
+	bounds  []float64
+	buckets []uint64 // per bucket, not cumulative; the last one is +Inf
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, buckets: make([]uint64, len(bounds)+1)}
+}
+
+// checkBounds reports an error unless bounds are finite and strictly
+// increasing, as the cumulative buckets of the exposition format require.
+// The +Inf bucket is always added and must not be listed
+func checkBounds(bounds []float64) error {
+	for i, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return fmt.Errorf("bucket bound %d is %v, want a finite value", i, b)
+		}
+		if i > 0 && b <= bounds[i-1] {
+			return fmt.Errorf("bucket bounds must be strictly increasing, got %v after %v", b, bounds[i-1])
+		}
+	}
+	return nil
+}
+
+// observe records a duration in seconds
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.buckets[i]++
+	h.count++
+	h.sum += v
+}
+
+// snapshot returns the cumulative bucket counts, the count and the sum
+func (h *histogram) snapshot() ([]uint64, uint64, float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	cumulative := make([]uint64, len(h.buckets))
+	var total uint64
+	for i, n := range h.buckets {
+		total += n
+		cumulative[i] = total
+	}
+	return cumulative, h.count, h.sum
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeHistogram writes the samples of one labeled histogram
+func writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	cumulative, count, sum := h.snapshot()
+	for i, n := range cumulative {
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = formatFloat(h.bounds[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, le, n)
+	}
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, count)
+}
+
+// formatFloat formats v the way the text exposition format expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
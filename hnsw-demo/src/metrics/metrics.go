@@ -0,0 +1,188 @@
+// Package metrics instruments an index and exposes the measurements in the
+// Prometheus text exposition format, without depending on a Prometheus
+// client library
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Operations, used as the value of the op label
+const (
+	OpInsert = "insert"
+	OpDelete = "delete"
+	OpSearch = "search"
+)
+
+var operations = []string{OpInsert, OpDelete, OpSearch}
+
+// Instrumented wraps an index, counting the operations made through it and
+// timing them. Operations made on the index directly are not recorded
+type Instrumented struct {
+	index *algorithm.HNSW
+
+	calls   map[string]*counter
+	errors  map[string]*counter
+	latency map[string]*histogram
+
+	distanceComputations counter
+	visitedNodes         counter
+	truncated            counter
+}
+
+// New instruments index using DefaultBuckets for the latency histograms
+func New(index *algorithm.HNSW) *Instrumented {
+	return newInstrumented(index, DefaultBuckets)
+}
+
+// NewWithBuckets instruments index using the given histogram upper bounds,
+// in seconds. The bounds must be finite and strictly increasing
+func NewWithBuckets(index *algorithm.HNSW, buckets []float64) (*Instrumented, error) {
+	if err := checkBounds(buckets); err != nil {
+		return nil, err
+	}
+	return newInstrumented(index, append([]float64(nil), buckets...)), nil
+}
+
+// newInstrumented instruments index with histograms of valid bounds
+func newInstrumented(index *algorithm.HNSW, buckets []float64) *Instrumented {
+	m := &Instrumented{
+		index:   index,
+		calls:   make(map[string]*counter, len(operations)),
+		errors:  make(map[string]*counter, len(operations)),
+		latency: make(map[string]*histogram, len(operations)),
+	}
+	for _, op := range operations {
+		m.calls[op] = &counter{}
+		m.errors[op] = &counter{}
+		m.latency[op] = newHistogram(buckets)
+	}
+	return m
+}
+
+// Index returns the wrapped index
+func (m *Instrumented) Index() *algorithm.HNSW {
+	return m.index
+}
+
+// record counts one call of op that started at start
+func (m *Instrumented) record(op string, start time.Time, err error) {
+	m.latency[op].observe(time.Since(start))
+	m.calls[op].add(1)
+	if err != nil {
+		m.errors[op].add(1)
+	}
+}
+
+// recordSearch counts the work done by one search
+func (m *Instrumented) recordSearch(stats algorithm.SearchStats) {
+	m.distanceComputations.add(stats.DistanceComputations)
+	m.visitedNodes.add(stats.VisitedNodes)
+	if stats.Truncated {
+		m.truncated.add(1)
+	}
+}
+
+// Insert adds a dense vector
+func (m *Instrumented) Insert(id int, vector []float64) error {
+	return m.InsertWithAttributes(id, vector, nil)
+}
+
+// InsertWithAttributes adds a dense vector with an attached payload
+func (m *Instrumented) InsertWithAttributes(id int, vector []float64, attrs map[string]string) error {
+	start := time.Now()
+	err := m.index.InsertWithAttributes(id, vector, attrs)
+	m.record(OpInsert, start, err)
+	return err
+}
+
+// InsertSparse adds a sparse vector
+func (m *Instrumented) InsertSparse(id int, vector sparse.Vector) error {
+	start := time.Now()
+	err := m.index.InsertSparse(id, vector)
+	m.record(OpInsert, start, err)
+	return err
+}
+
+// Delete removes an element
+func (m *Instrumented) Delete(id int) error {
+	start := time.Now()
+	err := m.index.Delete(id)
+	m.record(OpDelete, start, err)
+	return err
+}
+
+// Search runs a dense query
+func (m *Instrumented) Search(ctx context.Context, q []float64, opts algorithm.SearchOptions) ([]algorithm.Result, error) {
+	results, _, err := m.SearchWithStats(ctx, q, opts)
+	return results, err
+}
+
+// SearchWithStats runs a dense query and reports the work it used
+func (m *Instrumented) SearchWithStats(ctx context.Context, q []float64, opts algorithm.SearchOptions) ([]algorithm.Result, algorithm.SearchStats, error) {
+	start := time.Now()
+	results, stats, err := m.index.SearchWithStats(ctx, q, opts)
+	m.record(OpSearch, start, err)
+	m.recordSearch(stats)
+	return results, stats, err
+}
+
+// SearchSparse runs a sparse query
+func (m *Instrumented) SearchSparse(ctx context.Context, q sparse.Vector, opts algorithm.SearchOptions) ([]algorithm.Result, error) {
+	start := time.Now()
+	results, stats, err := m.index.SearchSparseWithStats(ctx, q, opts)
+	m.record(OpSearch, start, err)
+	m.recordSearch(stats)
+	return results, err
+}
+
+// WriteTo writes all metrics in the text exposition format
+func (m *Instrumented) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "hnsw_operations_total", "counter", "Operations made through the instrumented index.")
+	for _, op := range operations {
+		fmt.Fprintf(&buf, "hnsw_operations_total{op=%q} %d\n", op, m.calls[op].get())
+	}
+	writeHeader(&buf, "hnsw_operation_errors_total", "counter", "Operations that returned an error.")
+	for _, op := range operations {
+		fmt.Fprintf(&buf, "hnsw_operation_errors_total{op=%q} %d\n", op, m.errors[op].get())
+	}
+	writeHeader(&buf, "hnsw_operation_duration_seconds", "histogram", "Latency of operations.")
+	for _, op := range operations {
+		writeHistogram(&buf, "hnsw_operation_duration_seconds", fmt.Sprintf("op=%q", op), m.latency[op])
+	}
+
+	writeHeader(&buf, "hnsw_search_distance_computations_total", "counter", "Distance evaluations made by searches.")
+	fmt.Fprintf(&buf, "hnsw_search_distance_computations_total %d\n", m.distanceComputations.get())
+	writeHeader(&buf, "hnsw_search_visited_nodes_total", "counter", "Nodes visited by searches.")
+	fmt.Fprintf(&buf, "hnsw_search_visited_nodes_total %d\n", m.visitedNodes.get())
+	writeHeader(&buf, "hnsw_search_truncated_total", "counter", "Searches stopped early by a budget.")
+	fmt.Fprintf(&buf, "hnsw_search_truncated_total %d\n", m.truncated.get())
+
+	writeHeader(&buf, "hnsw_nodes", "gauge", "Elements in the index.")
+	fmt.Fprintf(&buf, "hnsw_nodes %d\n", m.index.Len())
+	writeHeader(&buf, "hnsw_max_level", "gauge", "Top layer of the graph.")
+	fmt.Fprintf(&buf, "hnsw_max_level %d\n", m.index.MaxLevel())
+
+	return buf.WriteTo(w)
+}
+
+// Handler serves the metrics for a Prometheus scrape
+func (m *Instrumented) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		m.WriteTo(w)
+	})
+}
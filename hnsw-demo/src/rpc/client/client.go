@@ -0,0 +1,193 @@
+// Package client is a Go client for the gRPC Index service
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/rpc/hnswpb"
+)
+
+// Client calls the Index service over a gRPC connection
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  hnswpb.IndexClient
+}
+
+// Query is a single search request. Filter keeps only elements whose
+// attributes contain all of its pairs
+type Query struct {
+	Vector  []float64
+	Options algorithm.SearchOptions
+	Filter  map[string]string
+}
+
+// Stats describes a remote index
+type Stats struct {
+	Count          int
+	Dimension      int
+	Metric         string
+	MaxLevel       int
+	M              int
+	MaxM           int
+	EfConstruction int
+}
+
+// BulkError reports an element of a bulk insert that was not inserted
+type BulkError struct {
+	Index int // position in the stream
+	ID    int
+	Error string
+}
+
+// Record is an element to insert
+type Record struct {
+	ID         int
+	Vector     []float64
+	Attributes map[string]string
+}
+
+// Dial connects to the Index service at target
+func Dial(target string, opts ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return New(conn), nil
+}
+
+// New creates a client using an existing connection
+func New(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn, rpc: hnswpb.NewIndexClient(conn)}
+}
+
+// Close closes the underlying connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Insert adds one element
+func (c *Client) Insert(ctx context.Context, id int, vector []float64, attrs map[string]string) error {
+	_, err := c.rpc.Insert(ctx, &hnswpb.InsertRequest{Id: int64(id), Vector: vector, Attributes: attrs})
+	return err
+}
+
+// Delete removes one element
+func (c *Client) Delete(ctx context.Context, id int) error {
+	_, err := c.rpc.Delete(ctx, &hnswpb.DeleteRequest{Id: int64(id)})
+	return err
+}
+
+// Search returns the nearest neighbors of q and the work the query used.
+// Filter of opts is ignored; use SearchFiltered to filter by attributes
+func (c *Client) Search(ctx context.Context, q []float64, opts algorithm.SearchOptions) ([]algorithm.Result, algorithm.SearchStats, error) {
+	return c.SearchFiltered(ctx, Query{Vector: q, Options: opts})
+}
+
+// SearchFiltered runs a query that may filter by attributes
+func (c *Client) SearchFiltered(ctx context.Context, q Query) ([]algorithm.Result, algorithm.SearchStats, error) {
+	resp, err := c.rpc.Search(ctx, searchRequest(q))
+	if err != nil {
+		return nil, algorithm.SearchStats{}, err
+	}
+	results, stats := fromResponse(resp)
+	return results, stats, nil
+}
+
+// BatchSearch runs several queries in one call, answering them in order
+func (c *Client) BatchSearch(ctx context.Context, queries []Query) ([][]algorithm.Result, error) {
+	req := &hnswpb.BatchSearchRequest{Queries: make([]*hnswpb.SearchRequest, len(queries))}
+	for i, q := range queries {
+		req.Queries[i] = searchRequest(q)
+	}
+
+	resp, err := c.rpc.BatchSearch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]algorithm.Result, len(resp.GetResponses()))
+	for i, r := range resp.GetResponses() {
+		results[i], _ = fromResponse(r)
+	}
+	return results, nil
+}
+
+// BulkInsert streams records to the server, returning the number inserted
+// and the records that failed
+func (c *Client) BulkInsert(ctx context.Context, records []Record) (int, []BulkError, error) {
+	stream, err := c.rpc.BulkInsert(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, r := range records {
+		req := &hnswpb.InsertRequest{Id: int64(r.ID), Vector: r.Vector, Attributes: r.Attributes}
+		if err := stream.Send(req); err != nil {
+			// The server ended the stream; CloseAndRecv reports why
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return 0, nil, err
+	}
+	errs := make([]BulkError, len(resp.GetErrors()))
+	for i, e := range resp.GetErrors() {
+		errs[i] = BulkError{Index: int(e.GetIndex()), ID: int(e.GetId()), Error: e.GetError()}
+	}
+	return int(resp.GetInserted()), errs, nil
+}
+
+// GetStats describes the remote index
+func (c *Client) GetStats(ctx context.Context) (Stats, error) {
+	resp, err := c.rpc.GetStats(ctx, &hnswpb.GetStatsRequest{})
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		Count:          int(resp.GetCount()),
+		Dimension:      int(resp.GetDimension()),
+		Metric:         resp.GetMetric(),
+		MaxLevel:       int(resp.GetMaxLevel()),
+		M:              int(resp.GetM()),
+		MaxM:           int(resp.GetMaxM()),
+		EfConstruction: int(resp.GetEfConstruction()),
+	}, nil
+}
+
+// searchRequest converts a query to its protobuf form
+func searchRequest(q Query) *hnswpb.SearchRequest {
+	return &hnswpb.SearchRequest{
+		Vector:                  q.Vector,
+		K:                       int32(q.Options.K),
+		Ef:                      int32(q.Options.Ef),
+		MaxDistance:             q.Options.MaxDistance,
+		Filter:                  q.Filter,
+		IncludeVectors:          q.Options.IncludeVectors,
+		IncludeAttributes:       q.Options.IncludeAttributes,
+		MaxDistanceComputations: int32(q.Options.MaxDistanceComputations),
+		MaxVisitedNodes:         int32(q.Options.MaxVisitedNodes),
+	}
+}
+
+// fromResponse converts a search response to results and stats
+func fromResponse(resp *hnswpb.SearchResponse) ([]algorithm.Result, algorithm.SearchStats) {
+	results := make([]algorithm.Result, len(resp.GetResults()))
+	for i, r := range resp.GetResults() {
+		results[i] = algorithm.Result{
+			ID:         int(r.GetId()),
+			Distance:   r.GetDistance(),
+			Vector:     r.GetVector(),
+			Attributes: r.GetAttributes(),
+		}
+	}
+	stats := resp.GetStats()
+	return results, algorithm.SearchStats{
+		DistanceComputations: int(stats.GetDistanceComputations()),
+		VisitedNodes:         int(stats.GetVisitedNodes()),
+		Hops:                 int(stats.GetHops()),
+		Truncated:            stats.GetTruncated(),
+	}
+}
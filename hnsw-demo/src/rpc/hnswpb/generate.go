@@ -0,0 +1,5 @@
+// Package hnswpb holds the protobuf messages and gRPC stubs of the index
+// service. Regenerate them from hnsw.proto with go generate
+package hnswpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative hnsw.proto
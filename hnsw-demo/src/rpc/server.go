@@ -0,0 +1,207 @@
+// Package rpc serves an index over gRPC using the Index service defined in
+// hnswpb/hnsw.proto
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/rpc/hnswpb"
+)
+
+// Server implements hnswpb.IndexServer on top of an index. Writes are
+// serialized against searches
+type Server struct {
+	hnswpb.UnimplementedIndexServer
+
+	mutex sync.RWMutex
+	index *algorithm.HNSW
+}
+
+// NewServer creates a server for index
+func NewServer(index *algorithm.HNSW) *Server {
+	return &Server{index: index}
+}
+
+// Register registers a server for index with s
+func Register(s *grpc.Server, index *algorithm.HNSW) *Server {
+	srv := NewServer(index)
+	hnswpb.RegisterIndexServer(s, srv)
+	return srv
+}
+
+// toStatus maps an error to the gRPC status reported for it
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, algorithm.ErrNodeNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, algorithm.ErrNodeExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, algorithm.ErrDimensionMismatch),
+		errors.Is(err, algorithm.ErrSparseIndex):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// Insert adds one element
+func (s *Server) Insert(ctx context.Context, req *hnswpb.InsertRequest) (*hnswpb.InsertResponse, error) {
+	if err := s.insert(req); err != nil {
+		return nil, toStatus(err)
+	}
+	return &hnswpb.InsertResponse{}, nil
+}
+
+// insert adds the element described by req
+func (s *Server) insert(req *hnswpb.InsertRequest) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.index.InsertWithAttributes(int(req.GetId()), req.GetVector(), req.GetAttributes())
+}
+
+// Delete removes one element
+func (s *Server) Delete(ctx context.Context, req *hnswpb.DeleteRequest) (*hnswpb.DeleteResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.index.Delete(int(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &hnswpb.DeleteResponse{}, nil
+}
+
+// Search returns the nearest neighbors of one query
+func (s *Server) Search(ctx context.Context, req *hnswpb.SearchRequest) (*hnswpb.SearchResponse, error) {
+	resp, err := s.search(ctx, req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return resp, nil
+}
+
+// BatchSearch runs several queries, answering them in order. The first
+// failing query fails the whole batch
+func (s *Server) BatchSearch(ctx context.Context, req *hnswpb.BatchSearchRequest) (*hnswpb.BatchSearchResponse, error) {
+	resp := &hnswpb.BatchSearchResponse{Responses: make([]*hnswpb.SearchResponse, 0, len(req.GetQueries()))}
+	for _, query := range req.GetQueries() {
+		result, err := s.search(ctx, query)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Responses = append(resp.Responses, result)
+	}
+	return resp, nil
+}
+
+// search runs one query against the index
+func (s *Server) search(ctx context.Context, req *hnswpb.SearchRequest) (*hnswpb.SearchResponse, error) {
+	if req.GetK() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "k must be positive, got %d", req.GetK())
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	results, stats, err := s.index.SearchWithStats(ctx, req.GetVector(), algorithm.SearchOptions{
+		K:                       int(req.GetK()),
+		Ef:                      int(req.GetEf()),
+		Filter:                  s.attributeFilter(req.GetFilter()),
+		IncludeVectors:          req.GetIncludeVectors(),
+		IncludeAttributes:       req.GetIncludeAttributes(),
+		MaxDistance:             req.GetMaxDistance(),
+		MaxDistanceComputations: int(req.GetMaxDistanceComputations()),
+		MaxVisitedNodes:         int(req.GetMaxVisitedNodes()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &hnswpb.SearchResponse{
+		Results: make([]*hnswpb.SearchResult, len(results)),
+		Stats: &hnswpb.SearchStats{
+			DistanceComputations: int64(stats.DistanceComputations),
+			VisitedNodes:         int64(stats.VisitedNodes),
+			Hops:                 int64(stats.Hops),
+			Truncated:            stats.Truncated,
+		},
+	}
+	for i, result := range results {
+		resp.Results[i] = &hnswpb.SearchResult{
+			Id:         int64(result.ID),
+			Distance:   result.Distance,
+			Vector:     result.Vector,
+			Attributes: result.Attributes,
+		}
+	}
+	return resp, nil
+}
+
+// attributeFilter returns a filter accepting the elements whose attributes
+// contain every key/value pair of match, or nil if match is empty
+func (s *Server) attributeFilter(match map[string]string) func(id int) bool {
+	if len(match) == 0 {
+		return nil
+	}
+	return func(id int) bool {
+		record, err := s.index.Get(id)
+		if err != nil {
+			return false
+		}
+		for k, v := range match {
+			if record.Attributes[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// BulkInsert adds a stream of elements. Elements are inserted as they
+// arrive; failures are reported per element once the stream ends
+func (s *Server) BulkInsert(stream grpc.ClientStreamingServer[hnswpb.InsertRequest, hnswpb.BulkInsertResponse]) error {
+	resp := &hnswpb.BulkInsertResponse{}
+	for index := int64(0); ; index++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.insert(req); err != nil {
+			resp.Errors = append(resp.Errors, &hnswpb.BulkInsertError{Index: index, Id: req.GetId(), Error: err.Error()})
+			continue
+		}
+		resp.Inserted++
+	}
+}
+
+// GetStats describes the index
+func (s *Server) GetStats(ctx context.Context, req *hnswpb.GetStatsRequest) (*hnswpb.GetStatsResponse, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cfg := s.index.Config()
+	return &hnswpb.GetStatsResponse{
+		Count:          int64(s.index.Len()),
+		Dimension:      int32(s.index.Dimension()),
+		Metric:         s.index.Metric(),
+		MaxLevel:       int32(s.index.MaxLevel()),
+		M:              int32(cfg.M),
+		MaxM:           int32(cfg.MaxM),
+		EfConstruction: int32(cfg.EfConstruction),
+	}, nil
+}
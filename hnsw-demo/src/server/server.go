@@ -0,0 +1,465 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/collection"
+)
+
+// maxBodyBytes bounds the size of a request body
+const maxBodyBytes = 64 << 20
+
+// Server exposes the collections of a manager over HTTP with JSON bodies
+type Server struct {
+	manager *collection.Manager
+	mux     *http.ServeMux
+}
+
+// New creates a server for the collections of manager
+func New(manager *collection.Manager) *Server {
+	s := &Server{manager: manager, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /collections", s.listCollections)
+	s.mux.HandleFunc("POST /collections", s.createCollection)
+	s.mux.HandleFunc("GET /collections/{name}", s.getCollection)
+	s.mux.HandleFunc("DELETE /collections/{name}", s.dropCollection)
+	s.mux.HandleFunc("GET /collections/{name}/stats", s.collectionStats)
+	s.mux.HandleFunc("POST /collections/{name}/snapshot", s.snapshotCollection)
+	s.mux.HandleFunc("POST /collections/{name}/tune", s.tuneCollection)
+
+	s.mux.HandleFunc("POST /collections/{name}/vectors", s.insertVector)
+	s.mux.HandleFunc("POST /collections/{name}/vectors/batch", s.insertBatch)
+	s.mux.HandleFunc("GET /collections/{name}/vectors/{id}", s.getVector)
+	s.mux.HandleFunc("PUT /collections/{name}/vectors/{id}", s.upsertVector)
+	s.mux.HandleFunc("DELETE /collections/{name}/vectors/{id}", s.deleteVector)
+
+	s.mux.HandleFunc("POST /collections/{name}/search", s.search)
+	s.mux.HandleFunc("POST /collections/{name}/range", s.rangeSearch)
+
+	s.mux.HandleFunc("POST /snapshot", s.snapshotAll)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// errBadRequest marks errors caused by a malformed request
+var errBadRequest = errors.New("bad request")
+
+// statusClientClosedRequest is the nonstandard status reported when the
+// client cancels a request before it completes
+const statusClientClosedRequest = 499
+
+// statusOf maps an error to the HTTP status reported for it
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest),
+		errors.Is(err, collection.ErrInvalidOptions),
+		errors.Is(err, algorithm.ErrDimensionMismatch),
+		errors.Is(err, algorithm.ErrSparseIndex),
+		errors.Is(err, algorithm.ErrDenseIndex):
+		return http.StatusBadRequest
+	case errors.Is(err, collection.ErrCollectionNotFound),
+		errors.Is(err, algorithm.ErrNodeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, collection.ErrCollectionExists),
+		errors.Is(err, algorithm.ErrNodeExists):
+		return http.StatusConflict
+	case errors.Is(err, algorithm.ErrRecallUnreachable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as {"error": "..."} with the status mapped from it
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusOf(err), ErrorResponse{Error: err.Error()})
+}
+
+// decode reads the JSON body of r into v, rejecting unknown fields
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid JSON body: %v", errBadRequest, err)
+	}
+	return nil
+}
+
+// pathID parses the {id} path segment
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid id %q", errBadRequest, r.PathValue("id"))
+	}
+	return id, nil
+}
+
+func (s *Server) listCollections(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ListCollectionsResponse{Collections: s.manager.List()})
+}
+
+func (s *Server) createCollection(w http.ResponseWriter, r *http.Request) {
+	var req CreateCollectionRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cfg := config.NewDefaultConfig()
+	if req.Config != nil {
+		var err error
+		cfg, err = config.NewConfig(req.Config.M, req.Config.MaxM, req.Config.EfConstruction, req.Config.DelayRebuild)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: %v", errBadRequest, err))
+			return
+		}
+		cfg.Seed = req.Config.Seed
+	}
+
+	c, err := s.manager.Create(req.Name, collection.Options{
+		Config:      cfg,
+		Metric:      req.Metric,
+		Dimension:   req.Dimension,
+		Description: req.Description,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c.Info())
+}
+
+func (s *Server) getCollection(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c.Info())
+}
+
+func (s *Server) dropCollection(w http.ResponseWriter, r *http.Request) {
+	if err := s.manager.Drop(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) collectionStats(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	info := c.Info()
+	stats := c.Stats()
+	resp := StatsResponse{
+		Name:        info.Name,
+		Metric:      info.Metric,
+		Dimension:   info.Dimension,
+		Count:       stats.Nodes,
+		Config:      info.Config,
+		DefaultEf:   c.Index().DefaultEf(),
+		Deleted:     stats.Deleted,
+		EntryPoint:  stats.EntryPoint,
+		MaxLevel:    stats.MaxLevel,
+		Unreachable: stats.Unreachable,
+		MemoryBytes: stats.MemoryBytes,
+		Levels:      make([]LevelStatsResponse, len(stats.Levels)),
+	}
+	for i, ls := range stats.Levels {
+		resp.Levels[i] = LevelStatsResponse(ls)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) snapshotCollection(w http.ResponseWriter, r *http.Request) {
+	if err := s.manager.Save(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) tuneCollection(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req TuneRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	switch {
+	case len(req.Queries) == 0:
+		err = fmt.Errorf("%w: queries must not be empty", errBadRequest)
+	case req.K <= 0:
+		err = fmt.Errorf("%w: k must be positive", errBadRequest)
+	case req.Recall <= 0 || req.Recall > 1:
+		err = fmt.Errorf("%w: recall must be in (0, 1]", errBadRequest)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	tuning, err := c.TuneEf(r.Context(), req.Queries, req.K, req.Recall)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := TuneResponse{Ef: tuning.Ef, Recall: tuning.Recall, Trials: make([]EfTrial, len(tuning.Trials))}
+	for i, trial := range tuning.Trials {
+		resp.Trials[i] = EfTrial(trial)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) snapshotAll(w http.ResponseWriter, r *http.Request) {
+	if err := s.manager.SaveAll(); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) insertVector(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req VectorRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.ID == nil {
+		writeError(w, fmt.Errorf("%w: missing id", errBadRequest))
+		return
+	}
+
+	if err := c.InsertWithAttributes(*req.ID, req.Vector, req.Attributes); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) insertBatch(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req BatchRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Items are inserted independently; failures are reported per item
+	resp := BatchResponse{Errors: []BatchError{}}
+	for i, item := range req.Vectors {
+		if item.ID == nil {
+			resp.Errors = append(resp.Errors, BatchError{Index: i, Status: http.StatusBadRequest, Error: "missing id"})
+			continue
+		}
+		if req.Upsert {
+			err = c.Upsert(*item.ID, item.Vector, item.Attributes)
+		} else {
+			err = c.InsertWithAttributes(*item.ID, item.Vector, item.Attributes)
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, BatchError{Index: i, ID: *item.ID, Status: statusOf(err), Error: err.Error()})
+			continue
+		}
+		resp.Inserted++
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) getVector(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	record, err := c.Get(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, RecordResponse{ID: record.ID, Vector: record.Vector, Attributes: record.Attributes})
+}
+
+func (s *Server) upsertVector(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req VectorRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.ID != nil && *req.ID != id {
+		writeError(w, fmt.Errorf("%w: body id %d does not match path id %d", errBadRequest, *req.ID, id))
+		return
+	}
+
+	if err := c.Upsert(id, req.Vector, req.Attributes); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteVector(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := c.Delete(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req SearchRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.K <= 0 {
+		writeError(w, fmt.Errorf("%w: k must be positive, got %d", errBadRequest, req.K))
+		return
+	}
+
+	results, err := c.Search(r.Context(), req.Vector, algorithm.SearchOptions{
+		K:                 req.K,
+		Ef:                req.Ef,
+		Filter:            attributeFilter(c, req.Filter),
+		IncludeVectors:    req.IncludeVectors,
+		IncludeAttributes: req.IncludeAttributes,
+		MaxDistance:       req.MaxDistance,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSearchResponse(results))
+}
+
+func (s *Server) rangeSearch(w http.ResponseWriter, r *http.Request) {
+	c, err := s.manager.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req RangeRequest
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Radius <= 0 {
+		writeError(w, fmt.Errorf("%w: radius must be positive", errBadRequest))
+		return
+	}
+	if req.Ef <= 0 {
+		req.Ef = 32
+	}
+
+	ids, distances, err := c.RangeSearch(r.Context(), req.Vector, req.Radius, req.Ef)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	accept := attributeFilter(c, req.Filter)
+	resp := SearchResponse{Results: []SearchResult{}}
+	for i, id := range ids {
+		if accept == nil || accept(id) {
+			resp.Results = append(resp.Results, SearchResult{ID: id, Distance: distances[i]})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// attributeFilter returns a filter accepting the elements whose attributes
+// contain every key/value pair of match, or nil if match is empty
+func attributeFilter(c *collection.Collection, match map[string]string) func(id int) bool {
+	if len(match) == 0 {
+		return nil
+	}
+	index := c.Index()
+	return func(id int) bool {
+		record, err := index.Get(id)
+		if err != nil {
+			return false
+		}
+		for k, v := range match {
+			if record.Attributes[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
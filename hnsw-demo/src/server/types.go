@@ -0,0 +1,172 @@
+package server
+
+import (
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/collection"
+)
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// ConfigRequest holds the index parameters of a new collection
+type ConfigRequest struct {
+	M              int   `json:"m"`
+	MaxM           int   `json:"max_m"`
+	EfConstruction int   `json:"ef_construction"`
+	DelayRebuild   bool  `json:"delay_rebuild"`
+	Seed           int64 `json:"seed,omitempty"`
+}
+
+// CreateCollectionRequest is the body of POST /collections. The default
+// config is used when Config is omitted
+type CreateCollectionRequest struct {
+	Name        string         `json:"name"`
+	Metric      string         `json:"metric"`
+	Dimension   int            `json:"dimension"`
+	Description string         `json:"description,omitempty"`
+	Config      *ConfigRequest `json:"config,omitempty"`
+}
+
+// ListCollectionsResponse is the body of GET /collections
+type ListCollectionsResponse struct {
+	Collections []collection.Info `json:"collections"`
+}
+
+// StatsResponse is the body of GET /collections/{name}/stats
+type StatsResponse struct {
+	Name      string        `json:"name"`
+	Metric    string        `json:"metric"`
+	Dimension int           `json:"dimension"`
+	Count     int           `json:"count"`
+	Config    config.Config `json:"config"`
+
+	// ef of searches that do not set one, 0 for 2*k
+	DefaultEf int `json:"default_ef"`
+
+	// Health of the graph
+	Deleted     int                  `json:"deleted"`
+	EntryPoint  int                  `json:"entry_point"`
+	MaxLevel    int                  `json:"max_level"`
+	Unreachable int                  `json:"unreachable"`
+	MemoryBytes int64                `json:"memory_bytes"`
+	Levels      []LevelStatsResponse `json:"levels"`
+}
+
+// LevelStatsResponse describes one layer of the graph. Histogram maps a
+// degree to the number of nodes having it
+type LevelStatsResponse struct {
+	Level       int         `json:"level"`
+	Nodes       int         `json:"nodes"`
+	MinDegree   int         `json:"min_degree"`
+	MeanDegree  float64     `json:"mean_degree"`
+	MaxDegree   int         `json:"max_degree"`
+	Histogram   map[int]int `json:"histogram"`
+	Unreachable int         `json:"unreachable"`
+}
+
+// VectorRequest is a single element to insert or upsert
+type VectorRequest struct {
+	ID         *int              `json:"id,omitempty"`
+	Vector     []float64         `json:"vector"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// BatchRequest is the body of POST /collections/{name}/vectors/batch
+type BatchRequest struct {
+	Vectors []VectorRequest `json:"vectors"`
+
+	// Replace elements that are already present instead of failing them
+	Upsert bool `json:"upsert,omitempty"`
+}
+
+// BatchError reports an element of a batch that was not inserted
+type BatchError struct {
+	Index  int    `json:"index"`
+	ID     int    `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// BatchResponse is the body of a batch insert
+type BatchResponse struct {
+	Inserted int          `json:"inserted"`
+	Errors   []BatchError `json:"errors"`
+}
+
+// RecordResponse is the body of GET /collections/{name}/vectors/{id}
+type RecordResponse struct {
+	ID         int               `json:"id"`
+	Vector     []float64         `json:"vector"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SearchRequest is the body of POST /collections/{name}/search. Filter
+// keeps only elements whose attributes contain all of its pairs
+type SearchRequest struct {
+	Vector            []float64         `json:"vector"`
+	K                 int               `json:"k"`
+	Ef                int               `json:"ef,omitempty"`
+	Filter            map[string]string `json:"filter,omitempty"`
+	MaxDistance       float64           `json:"max_distance,omitempty"`
+	IncludeVectors    bool              `json:"include_vectors,omitempty"`
+	IncludeAttributes bool              `json:"include_attributes,omitempty"`
+}
+
+// RangeRequest is the body of POST /collections/{name}/range
+type RangeRequest struct {
+	Vector []float64         `json:"vector"`
+	Radius float64           `json:"radius"`
+	Ef     int               `json:"ef,omitempty"`
+	Filter map[string]string `json:"filter,omitempty"`
+}
+
+// TuneRequest is the body of POST /collections/{name}/tune
+type TuneRequest struct {
+	Queries [][]float64 `json:"queries"`
+	K       int         `json:"k"`
+	Recall  float64     `json:"recall"`
+}
+
+// EfTrial is the recall measured at one ef
+type EfTrial struct {
+	Ef     int     `json:"ef"`
+	Recall float64 `json:"recall"`
+}
+
+// TuneResponse is the body of a successful tuning. Ef is now the default
+// ef of the collection
+type TuneResponse struct {
+	Ef     int       `json:"ef"`
+	Recall float64   `json:"recall"`
+	Trials []EfTrial `json:"trials"`
+}
+
+// SearchResult is a single hit
+type SearchResult struct {
+	ID         int               `json:"id"`
+	Distance   float64           `json:"distance"`
+	Vector     []float64         `json:"vector,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SearchResponse is the body of a search or range search
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+}
+
+// newSearchResponse converts search results to their JSON form
+func newSearchResponse(results []algorithm.Result) SearchResponse {
+	resp := SearchResponse{Results: make([]SearchResult, len(results))}
+	for i, result := range results {
+		resp.Results[i] = SearchResult{
+			ID:         result.ID,
+			Distance:   result.Distance,
+			Vector:     result.Vector,
+			Attributes: result.Attributes,
+		}
+	}
+	return resp
+}
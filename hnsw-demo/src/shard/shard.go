@@ -0,0 +1,313 @@
+// Package shard spreads an index over several in-process HNSW graphs. Each
+// id lives in the shard its hash selects, searches query every shard in
+// parallel and merge their results, and every shard is saved to and
+// loaded from its own file
+package shard
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// Index is a dense index split into shards by id hash. Writes to
+// different shards may run concurrently, and reads run concurrently with
+// each other but not with writes to the same shard
+type Index struct {
+	mutex  sync.RWMutex // guards the shards slice against LoadShard
+	shards []*algorithm.HNSW
+	locks  []sync.RWMutex // serialize writes per shard against other access
+
+	// Dimension shared by all shards, fixed by the first insert
+	dimMutex  sync.Mutex
+	dimension int
+}
+
+// New creates an index of n empty shards with the given config and
+// metric. A nonzero cfg.Seed seeds shard i with cfg.Seed+i
+func New(n int, cfg config.Config, metric string) (*Index, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("shard count must be positive, got %d", n)
+	}
+	shards := make([]*algorithm.HNSW, n)
+	for i := range shards {
+		shardCfg := cfg
+		if cfg.Seed != 0 {
+			shardCfg.Seed = cfg.Seed + int64(i)
+		}
+		h, err := algorithm.New(shardCfg, metric)
+		if err != nil {
+			return nil, err
+		}
+		shards[i] = h
+	}
+	return newIndex(shards)
+}
+
+// newIndex wraps shards, checking that they agree on metric and dimension
+func newIndex(shards []*algorithm.HNSW) (*Index, error) {
+	s := &Index{shards: shards, locks: make([]sync.RWMutex, len(shards))}
+	for i, h := range shards {
+		if err := s.compatible(h); err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		if s.dimension == 0 {
+			s.dimension = h.Dimension()
+		}
+	}
+	return s, nil
+}
+
+// compatible reports why h cannot be a shard of s, if it cannot
+func (s *Index) compatible(h *algorithm.HNSW) error {
+	if h.IsSparse() {
+		return algorithm.ErrSparseIndex
+	}
+	if metric := s.Shard(0).Metric(); h.Metric() != metric {
+		return fmt.Errorf("metric %s does not match %s", h.Metric(), metric)
+	}
+	s.dimMutex.Lock()
+	defer s.dimMutex.Unlock()
+	if d := h.Dimension(); d != 0 && s.dimension != 0 && d != s.dimension {
+		return fmt.Errorf("%w: expected %d, got %d", algorithm.ErrDimensionMismatch, s.dimension, d)
+	}
+	return nil
+}
+
+// Shards returns the number of shards
+func (s *Index) Shards() int {
+	return len(s.shards)
+}
+
+// Shard returns shard i. Writes made on it directly must keep to the ids
+// ShardFor assigns to it
+func (s *Index) Shard(i int) *algorithm.HNSW {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.shards[i]
+}
+
+// all returns a snapshot of the shards
+func (s *Index) all() []*algorithm.HNSW {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]*algorithm.HNSW(nil), s.shards...)
+}
+
+// ShardFor returns the shard id belongs to
+func (s *Index) ShardFor(id int) int {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(id))
+	hash := fnv.New64a()
+	hash.Write(buf[:])
+	return int(hash.Sum64() % uint64(len(s.shards)))
+}
+
+// Len returns the number of elements across all shards
+func (s *Index) Len() int {
+	total := 0
+	for _, h := range s.all() {
+		total += h.Len()
+	}
+	return total
+}
+
+// Dimension returns the dimension of the stored vectors, 0 before the
+// first insert
+func (s *Index) Dimension() int {
+	s.dimMutex.Lock()
+	defer s.dimMutex.Unlock()
+	return s.dimension
+}
+
+// checkDimension fixes the dimension on the first insert and rejects
+// vectors of any other dimension afterwards
+func (s *Index) checkDimension(vector []float64) error {
+	s.dimMutex.Lock()
+	defer s.dimMutex.Unlock()
+	if s.dimension == 0 {
+		s.dimension = len(vector)
+	} else if len(vector) != s.dimension {
+		return fmt.Errorf("%w: expected %d, got %d", algorithm.ErrDimensionMismatch, s.dimension, len(vector))
+	}
+	return nil
+}
+
+// Insert adds a vector to the shard of id
+func (s *Index) Insert(id int, vector []float64) error {
+	return s.InsertWithAttributes(id, vector, nil)
+}
+
+// InsertWithAttributes adds a vector with an attached payload to the
+// shard of id
+func (s *Index) InsertWithAttributes(id int, vector []float64, attrs map[string]string) error {
+	if err := s.checkDimension(vector); err != nil {
+		return err
+	}
+	i := s.ShardFor(id)
+	s.locks[i].Lock()
+	defer s.locks[i].Unlock()
+	return s.Shard(i).InsertWithAttributes(id, vector, attrs)
+}
+
+// Delete removes id from its shard
+func (s *Index) Delete(id int) error {
+	i := s.ShardFor(id)
+	s.locks[i].Lock()
+	defer s.locks[i].Unlock()
+	return s.Shard(i).Delete(id)
+}
+
+// Get returns the stored vector and attributes of id
+func (s *Index) Get(id int) (algorithm.Record, error) {
+	i := s.ShardFor(id)
+	s.locks[i].RLock()
+	defer s.locks[i].RUnlock()
+	return s.Shard(i).Get(id)
+}
+
+// Search runs a query against every shard and returns the K closest
+// results among them
+func (s *Index) Search(ctx context.Context, q []float64, opts algorithm.SearchOptions) ([]algorithm.Result, error) {
+	results, _, err := s.SearchWithStats(ctx, q, opts)
+	return results, err
+}
+
+// SearchWithStats is Search that also reports the work done, summed over
+// the shards. Each shard is searched with the same options in its own
+// goroutine; the first shard error in shard order is returned
+func (s *Index) SearchWithStats(ctx context.Context, q []float64, opts algorithm.SearchOptions) ([]algorithm.Result, algorithm.SearchStats, error) {
+	if opts.K <= 0 {
+		return []algorithm.Result{}, algorithm.SearchStats{}, fmt.Errorf("K must be positive, got %d", opts.K)
+	}
+	shards := s.all()
+	perShard := make([][]algorithm.Result, len(shards))
+	shardStats := make([]algorithm.SearchStats, len(shards))
+	errs := make([]error, len(shards))
+
+	var wg sync.WaitGroup
+	for i, h := range shards {
+		if h.Len() == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, h *algorithm.HNSW) {
+			defer wg.Done()
+			s.locks[i].RLock()
+			defer s.locks[i].RUnlock()
+			perShard[i], shardStats[i], errs[i] = h.SearchWithStats(ctx, q, opts)
+		}(i, h)
+	}
+	wg.Wait()
+
+	var stats algorithm.SearchStats
+	results := []algorithm.Result{}
+	for i := range shards {
+		if errs[i] != nil {
+			return []algorithm.Result{}, stats, fmt.Errorf("shard %d: %w", i, errs[i])
+		}
+		stats.DistanceComputations += shardStats[i].DistanceComputations
+		stats.VisitedNodes += shardStats[i].VisitedNodes
+		stats.Hops += shardStats[i].Hops
+		stats.Truncated = stats.Truncated || shardStats[i].Truncated
+		results = append(results, perShard[i]...)
+	}
+	return mergeResults(results, opts.K), stats, nil
+}
+
+// mergeResults returns the K closest of results, ties broken by id
+func mergeResults(results []algorithm.Result, K int) []algorithm.Result {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].ID < results[j].ID
+	})
+	if K > 0 && len(results) > K {
+		results = results[:K]
+	}
+	return results
+}
+
+// ShardFile returns the file shard i is saved to under dir
+func ShardFile(dir string, i int) string {
+	return filepath.Join(dir, fmt.Sprintf("shard-%03d.hnsw", i))
+}
+
+// Save writes every shard to its ShardFile under dir, creating dir if
+// needed
+func (s *Index) Save(dir string, description string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for i := range s.all() {
+		if err := s.SaveShard(i, ShardFile(dir, i), description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveShard writes shard i alone to filename
+func (s *Index) SaveShard(i int, filename string, description string) error {
+	s.locks[i].Lock()
+	defer s.locks[i].Unlock()
+	if err := s.Shard(i).Save(filename, description); err != nil {
+		return fmt.Errorf("shard %d: %w", i, err)
+	}
+	return nil
+}
+
+// Load reads the shards saved by Save under dir. The shard count is the
+// number of consecutive shard files found
+func Load(dir string) (*Index, error) {
+	var shards []*algorithm.HNSW
+	for i := 0; ; i++ {
+		filename := ShardFile(dir, i)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			break
+		}
+		h, err := algorithm.Load(filename)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		shards = append(shards, h)
+	}
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("no shard files in %s", dir)
+	}
+	return newIndex(shards)
+}
+
+// LoadShard replaces shard i with the index saved in filename, which must
+// hold only ids ShardFor assigns to shard i
+func (s *Index) LoadShard(i int, filename string) error {
+	h, err := algorithm.Load(filename)
+	if err != nil {
+		return fmt.Errorf("shard %d: %w", i, err)
+	}
+	if err := s.compatible(h); err != nil {
+		return fmt.Errorf("shard %d: %w", i, err)
+	}
+
+	s.locks[i].Lock()
+	defer s.locks[i].Unlock()
+	s.mutex.Lock()
+	s.shards[i] = h
+	s.mutex.Unlock()
+
+	s.dimMutex.Lock()
+	defer s.dimMutex.Unlock()
+	if s.dimension == 0 {
+		s.dimension = h.Dimension()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package sweep
+
+import "sort"
+
+// dominates reports whether a is at least as good as b in recall, QPS,
+// build time and memory, and better in at least one
+func dominates(a, b Result) bool {
+	if a.Recall < b.Recall || a.QPS < b.QPS || a.BuildTime > b.BuildTime || a.MemoryBytes > b.MemoryBytes {
+		return false
+	}
+	return a.Recall > b.Recall || a.QPS > b.QPS || a.BuildTime < b.BuildTime || a.MemoryBytes < b.MemoryBytes
+}
+
+// Frontier returns the Pareto frontier of results: those for which no other
+// result is at least as good in recall, QPS, build time and memory and
+// better in one of them. They are ordered by decreasing recall, then
+// decreasing QPS
+func Frontier(results []Result) []Result {
+	var frontier []Result
+	for i, r := range results {
+		dominated := false
+		for j, other := range results {
+			if i != j && dominates(other, r) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			frontier = append(frontier, r)
+		}
+	}
+
+	sort.SliceStable(frontier, func(i, j int) bool {
+		if frontier[i].Recall != frontier[j].Recall {
+			return frontier[i].Recall > frontier[j].Recall
+		}
+		return frontier[i].QPS > frontier[j].QPS
+	})
+	return frontier
+}
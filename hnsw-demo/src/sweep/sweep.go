@@ -0,0 +1,170 @@
+// Package sweep measures how the construction parameters of an index trade
+// build time and memory against recall and query throughput on a sample of
+// a dataset
+package sweep
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// Params are the construction parameters of one index
+type Params struct {
+	M              int
+	MaxM           int
+	EfConstruction int
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf("M=%d MaxM=%d EfConstruction=%d", p.M, p.MaxM, p.EfConstruction)
+}
+
+// Space lists the values tried for each parameter. An empty MaxM uses 2*M
+type Space struct {
+	M              []int
+	MaxM           []int
+	EfConstruction []int
+}
+
+// Grid returns every combination of the values of space, skipping those
+// with MaxM < M
+func Grid(space Space) []Params {
+	var params []Params
+	for _, m := range space.M {
+		maxMs := space.MaxM
+		if len(maxMs) == 0 {
+			maxMs = []int{2 * m}
+		}
+		for _, maxM := range maxMs {
+			if maxM < m {
+				continue
+			}
+			for _, ef := range space.EfConstruction {
+				params = append(params, Params{M: m, MaxM: maxM, EfConstruction: ef})
+			}
+		}
+	}
+	return params
+}
+
+// Random returns up to n distinct combinations drawn from the grid of
+// space, in random order
+func Random(space Space, n int, rng *rand.Rand) []Params {
+	grid := Grid(space)
+	rng.Shuffle(len(grid), func(i, j int) { grid[i], grid[j] = grid[j], grid[i] })
+	return grid[:min(n, len(grid))]
+}
+
+// Options controls how each index is built and measured
+type Options struct {
+	// Metric of the indexes
+	Metric string
+
+	// Neighbors per query and the ef queries are run with. Ef 0 uses 2*K
+	K  int
+	Ef int
+
+	// Seed of the level generator of every index, so that runs differ only
+	// in their parameters. Zero uses a random seed per index
+	Seed int64
+}
+
+// Result is the measurement of one parameter combination
+type Result struct {
+	Params Params
+
+	BuildTime   time.Duration
+	MemoryBytes int64
+
+	// Mean recall of the queries at K
+	Recall float64
+
+	// Queries per second, searching one query at a time. Zero if the
+	// searches were too fast for the clock to measure
+	QPS float64
+}
+
+// Run builds one index over base for each combination of params and
+// measures it with queries. The true neighbors are computed once by brute
+// force. Combinations are measured one after another so that their
+// timings do not interfere
+func Run(ctx context.Context, base, queries [][]float64, params []Params, opts Options) ([]Result, error) {
+	if len(base) == 0 || len(queries) == 0 {
+		return nil, fmt.Errorf("base and queries must not be empty")
+	}
+	if opts.K <= 0 {
+		return nil, fmt.Errorf("K must be positive, got %d", opts.K)
+	}
+
+	var truth []map[int]bool
+	results := make([]Result, 0, len(params))
+	for _, p := range params {
+		index, buildTime, err := build(ctx, base, p, opts)
+		if err != nil {
+			return results, fmt.Errorf("%v: %w", p, err)
+		}
+
+		if truth == nil {
+			if truth, err = index.GroundTruth(ctx, queries, opts.K); err != nil {
+				return results, err
+			}
+		}
+
+		recall, qps, err := measure(ctx, index, queries, truth, opts)
+		if err != nil {
+			return results, fmt.Errorf("%v: %w", p, err)
+		}
+		results = append(results, Result{
+			Params:      p,
+			BuildTime:   buildTime,
+			MemoryBytes: index.Stats().MemoryBytes,
+			Recall:      recall,
+			QPS:         qps,
+		})
+	}
+	return results, nil
+}
+
+// build inserts base into a new index with the parameters p
+func build(ctx context.Context, base [][]float64, p Params, opts Options) (*algorithm.HNSW, time.Duration, error) {
+	cfg, err := config.NewConfig(p.M, p.MaxM, p.EfConstruction, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	cfg.Seed = opts.Seed
+	index, err := algorithm.New(cfg, opts.Metric)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	began := time.Now()
+	for i, v := range base {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, 0, err
+			}
+		}
+		if err := index.Insert(i, v); err != nil {
+			return nil, 0, fmt.Errorf("vector %d: %w", i, err)
+		}
+	}
+	return index, time.Since(began), nil
+}
+
+// measure returns the recall and throughput of the queries against index
+func measure(ctx context.Context, index *algorithm.HNSW, queries [][]float64, truth []map[int]bool, opts Options) (float64, float64, error) {
+	recall, elapsed, err := index.SampleRecall(ctx, queries, truth, algorithm.SearchOptions{K: opts.K, Ef: opts.Ef})
+	if err != nil {
+		return 0, 0, err
+	}
+	qps := 0.0
+	if elapsed > 0 {
+		qps = float64(len(queries)) / elapsed.Seconds()
+	}
+	return recall, qps, nil
+}
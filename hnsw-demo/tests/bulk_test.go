@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// bulkRecords returns n random 8-dimensional records numbered from 0
+func bulkRecords(rng *rand.Rand, n int) []algorithm.Record {
+	records := make([]algorithm.Record, n)
+	for i := range records {
+		records[i] = algorithm.Record{ID: i, Vector: randomVector(rng, 8)}
+	}
+	return records
+}
+
+// bulkRecall is the mean recall@10 of queries against exact search
+func bulkRecall(t *testing.T, hnsw *algorithm.HNSW, queries [][]float64) float64 {
+	t.Helper()
+
+	ctx := context.Background()
+	total := 0.0
+	for _, q := range queries {
+		exact, err := hnsw.ExactSearch(ctx, q, 10)
+		if err != nil {
+			t.Fatalf("ExactSearch failed: %v", err)
+		}
+		results, err := hnsw.Search(ctx, q, algorithm.SearchOptions{K: 10, Ef: 50})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		want := make(map[int]bool)
+		for _, r := range exact {
+			want[r.ID] = true
+		}
+		for _, r := range results {
+			if want[r.ID] {
+				total++
+			}
+		}
+	}
+	return total / float64(10*len(queries))
+}
+
+// structuralProblems returns the problems of report other than unreachable
+// nodes, which pruning can cause in any graph and Repair fixes
+func structuralProblems(report algorithm.VerifyReport) []algorithm.Problem {
+	var problems []algorithm.Problem
+	for _, p := range report.Problems {
+		if p.Kind != algorithm.ProblemUnreachable {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+func TestBulkLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	records := bulkRecords(rng, 2000)
+	queries := make([][]float64, 50)
+	for i := range queries {
+		queries[i] = randomVector(rng, 8)
+	}
+
+	for _, order := range []algorithm.BulkOrder{algorithm.OrderInput, algorithm.OrderShuffle, algorithm.OrderCluster} {
+		t.Run(order.String(), func(t *testing.T) {
+			cfg := config.NewDefaultConfig()
+			cfg.M, cfg.MaxM, cfg.EfConstruction = 8, 16, 60
+			cfg.Seed = 3
+			hnsw, err := algorithm.New(cfg, distance.Euclidean)
+			if err != nil {
+				t.Fatalf("Failed to create HNSW: %v", err)
+			}
+
+			if err := hnsw.BulkLoad(context.Background(), records, algorithm.BulkOptions{Workers: 4, Order: order}); err != nil {
+				t.Fatalf("BulkLoad failed: %v", err)
+			}
+			if hnsw.Len() != len(records) || hnsw.Dimension() != 8 {
+				t.Fatalf("got %d elements of dimension %d", hnsw.Len(), hnsw.Dimension())
+			}
+			if report := hnsw.Verify(); len(structuralProblems(report)) > 0 {
+				t.Errorf("bulk loaded graph is invalid:\n%s", report)
+			}
+			if recall := bulkRecall(t, hnsw, queries); recall < 0.9 {
+				t.Errorf("recall@10 %.3f, want at least 0.9", recall)
+			}
+
+			// The index stays mutable
+			if err := hnsw.Insert(len(records), randomVector(rng, 8)); err != nil {
+				t.Errorf("Insert after BulkLoad failed: %v", err)
+			}
+			if err := hnsw.Delete(0); err != nil {
+				t.Errorf("Delete after BulkLoad failed: %v", err)
+			}
+			if report := hnsw.Verify(); len(structuralProblems(report)) > 0 || report.Nodes != len(records) {
+				t.Errorf("graph is invalid after updates:\n%s", report)
+			}
+		})
+	}
+}
+
+func TestBulkLoadErrors(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	ctx := context.Background()
+
+	duplicate := []algorithm.Record{{ID: 1, Vector: []float64{0, 0}}, {ID: 1, Vector: []float64{1, 1}}}
+	if err := hnsw.BulkLoad(ctx, duplicate, algorithm.BulkOptions{}); !errors.Is(err, algorithm.ErrNodeExists) {
+		t.Errorf("duplicate ids: got %v, want ErrNodeExists", err)
+	}
+	mixed := []algorithm.Record{{ID: 1, Vector: []float64{0, 0}}, {ID: 2, Vector: []float64{1}}}
+	if err := hnsw.BulkLoad(ctx, mixed, algorithm.BulkOptions{}); !errors.Is(err, algorithm.ErrDimensionMismatch) {
+		t.Errorf("mixed dimensions: got %v, want ErrDimensionMismatch", err)
+	}
+	if hnsw.Len() != 0 {
+		t.Fatalf("failed loads left %d elements", hnsw.Len())
+	}
+
+	// A cancelled load leaves the index empty
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := hnsw.BulkLoad(cancelled, bulkRecords(rand.New(rand.NewSource(1)), 500), algorithm.BulkOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled load: got %v, want context.Canceled", err)
+	}
+	if hnsw.Len() != 0 {
+		t.Errorf("cancelled load left %d elements", hnsw.Len())
+	}
+
+	if err := hnsw.Insert(1, []float64{0, 0}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := hnsw.BulkLoad(ctx, duplicate[:1], algorithm.BulkOptions{}); err == nil {
+		t.Error("BulkLoad into a non-empty index succeeded")
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/collection"
+)
+
+func TestCollectionManager(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := collection.Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	cfg := config.NewDefaultConfig()
+	tenantA, err := manager.Create("tenant-a", collection.Options{Config: cfg, Metric: distance.Euclidean, Dimension: 2})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := manager.Create("tenant-b", collection.Options{Config: cfg, Metric: distance.Cosine, Dimension: 3}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := manager.Create("tenant-a", collection.Options{Config: cfg, Metric: distance.Euclidean, Dimension: 2}); !errors.Is(err, collection.ErrCollectionExists) {
+		t.Errorf("got %v, want ErrCollectionExists", err)
+	}
+	if _, err := manager.Create("../escape", collection.Options{Config: cfg, Metric: distance.Euclidean, Dimension: 2}); !errors.Is(err, collection.ErrInvalidOptions) {
+		t.Errorf("got %v, want ErrInvalidOptions for an invalid name", err)
+	}
+	if _, err := manager.Create("nometric", collection.Options{Config: cfg, Metric: "no-such-metric", Dimension: 2}); !errors.Is(err, collection.ErrInvalidOptions) {
+		t.Errorf("got %v, want ErrInvalidOptions for an unknown metric", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		if err := tenantA.Insert(i, []float64{float64(i), 0}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := tenantA.Insert(11, []float64{1, 2, 3}); !errors.Is(err, algorithm.ErrDimensionMismatch) {
+		t.Errorf("got %v, want ErrDimensionMismatch", err)
+	}
+
+	infos := manager.List()
+	if len(infos) != 2 || infos[0].Name != "tenant-a" || infos[1].Metric != distance.Cosine {
+		t.Errorf("unexpected collections %+v", infos)
+	}
+
+	if err := manager.SaveAll(); err != nil {
+		t.Fatalf("SaveAll failed: %v", err)
+	}
+
+	// Reopen and check the saved and never-filled collections
+	reopened, err := collection.Open(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	loaded, err := reopened.Get("tenant-a")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	results, err := loaded.Search(context.Background(), []float64{3.1, 0}, algorithm.SearchOptions{K: 1})
+	if err != nil || len(results) != 1 || results[0].ID != 3 {
+		t.Errorf("got %+v, %v, want id 3", results, err)
+	}
+
+	empty, err := reopened.Get("tenant-b")
+	if err != nil || empty.Len() != 0 || empty.Info().Dimension != 3 {
+		t.Fatalf("unexpected empty collection %v, %v", empty, err)
+	}
+	if err := empty.Insert(1, []float64{1, 0, 0}); err != nil {
+		t.Errorf("Insert into reopened empty collection failed: %v", err)
+	}
+
+	if err := reopened.Drop("tenant-a"); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	if _, err := reopened.Get("tenant-a"); !errors.Is(err, collection.ErrCollectionNotFound) {
+		t.Errorf("got %v, want ErrCollectionNotFound", err)
+	}
+
+	final, err := collection.Open(dir)
+	if err != nil || len(final.List()) != 1 {
+		t.Errorf("got %v, %v, want one collection after drop", final.List(), err)
+	}
+}
+
+func TestCollectionManagerOpenErrors(t *testing.T) {
+	// Open wraps the errors it runs into
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := collection.Open(filepath.Join(dir, "file", "data")); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("data directory under a file: got %v, want ENOTDIR", err)
+	}
+
+	// A collection whose index file holds sparse vectors is rejected
+	index, err := algorithm.NewSparse(config.NewDefaultConfig(), distance.DotProduct)
+	if err != nil {
+		t.Fatalf("NewSparse failed: %v", err)
+	}
+	v, _ := sparse.FromMap(map[int]float64{3: 1})
+	if err := index.InsertSparse(1, v); err != nil {
+		t.Fatalf("InsertSparse failed: %v", err)
+	}
+	if err := index.Save(filepath.Join(dir, "words.hnsw"), ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	mf := `{"collections": [{"name": "words", "metric": "dot", "dimension": 1, "file": "words.hnsw"}]}`
+	if err := os.WriteFile(filepath.Join(dir, collection.ManifestFile), []byte(mf), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := collection.Open(dir); !errors.Is(err, algorithm.ErrSparseIndex) {
+		t.Errorf("sparse index file: got %v, want ErrSparseIndex", err)
+	}
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+    "context"
+    "errors"
     "testing"
 
     "github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
@@ -42,22 +44,28 @@ func TestInsertAndSearch(t *testing.T) {
     // Test search
     query := []float64{1.1, 1.1, 1.1}
     k := 2
-    results, distances := hnsw.Search(query, k)
+    results, err := hnsw.Search(context.Background(), query, algorithm.SearchOptions{K: k, IncludeVectors: true})
+    if err != nil {
+        t.Fatalf("Search failed: %v", err)
+    }
 
     // Verify results
     if len(results) != k {
-        t.Errorf("Expected %d results, got %d", k, len(results))
+        t.Fatalf("Expected %d results, got %d", k, len(results))
     }
 
-    if results[0] != 1 { // Closest should be vector 1
-        t.Errorf("Expected closest vector to be 1, got %d", results[0])
+    if results[0].ID != 1 { // Closest should be vector 1
+        t.Errorf("Expected closest vector to be 1, got %d", results[0].ID)
     }
 
-    // Verify distances are sorted
-    for i := 1; i < len(distances); i++ {
-        if distances[i] < distances[i-1] {
+    // Verify distances are sorted and vectors are attached
+    for i, result := range results {
+        if i > 0 && result.Distance < results[i-1].Distance {
             t.Error("Distances are not sorted")
         }
+        if len(result.Vector) != len(vectors[result.ID]) {
+            t.Errorf("Expected vector of result %d to be included", result.ID)
+        }
     }
 }
 
@@ -69,9 +77,9 @@ func TestEmptySearch(t *testing.T) {
     }
 
     query := []float64{1.0, 1.0, 1.0}
-    results, distances := hnsw.Search(query, 1)
+    results, err := hnsw.Search(context.Background(), query, algorithm.SearchOptions{K: 1})
 
-    if len(results) != 0 || len(distances) != 0 {
+    if err != nil || results == nil || len(results) != 0 {
         t.Error("Empty index should return empty results")
     }
 }
@@ -96,4 +104,54 @@ func TestDuplicateInsert(t *testing.T) {
     if err == nil {
         t.Error("Expected error on duplicate insert")
     }
-}
\ No newline at end of file
+}
+func TestGetAndAttributes(t *testing.T) {
+    cfg := config.NewDefaultConfig()
+    hnsw, err := algorithm.New(cfg, distance.Euclidean)
+    if err != nil {
+        t.Fatalf("Failed to create HNSW: %v", err)
+    }
+
+    if err := hnsw.InsertWithAttributes(1, []float64{1.0, 0.0}, map[string]string{"color": "red"}); err != nil {
+        t.Fatalf("Insert failed: %v", err)
+    }
+    if err := hnsw.Insert(2, []float64{0.0, 1.0}); err != nil {
+        t.Fatalf("Insert failed: %v", err)
+    }
+    if err := hnsw.SetAttributes(2, map[string]string{"color": "blue"}); err != nil {
+        t.Fatalf("SetAttributes failed: %v", err)
+    }
+
+    record, err := hnsw.Get(1)
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if record.Vector[0] != 1.0 || record.Attributes["color"] != "red" {
+        t.Errorf("Unexpected record: %+v", record)
+    }
+
+    // Returned records are copies
+    record.Vector[0] = 5.0
+    record.Attributes["color"] = "green"
+    if again, _ := hnsw.Get(1); again.Vector[0] != 1.0 || again.Attributes["color"] != "red" {
+        t.Error("Modifying a record changed the index")
+    }
+
+    records, err := hnsw.GetBatch([]int{2, 1})
+    if err != nil || len(records) != 2 || records[0].ID != 2 || records[0].Attributes["color"] != "blue" {
+        t.Errorf("Unexpected batch: %+v, %v", records, err)
+    }
+
+    if _, err := hnsw.GetBatch([]int{1, 3}); !errors.Is(err, algorithm.ErrNodeNotFound) {
+        t.Errorf("Expected ErrNodeNotFound, got %v", err)
+    }
+
+    results, err := hnsw.Search(context.Background(), []float64{0.1, 0.9},
+        algorithm.SearchOptions{K: 1, IncludeVectors: true, IncludeAttributes: true})
+    if err != nil || len(results) != 1 {
+        t.Fatalf("Search failed: %v", err)
+    }
+    if results[0].ID != 2 || results[0].Attributes["color"] != "blue" || len(results[0].Vector) != 2 {
+        t.Errorf("Unexpected result: %+v", results[0])
+    }
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/dataset"
+)
+
+var datasetVectors = [][]float64{{1, 2, 3}, {-0.5, 0.25, 8}}
+
+// checkVectors compares vectors read from a dataset with datasetVectors
+func checkVectors(t *testing.T, got [][]float64) {
+	t.Helper()
+	if len(got) != len(datasetVectors) {
+		t.Fatalf("got %d vectors, want %d", len(got), len(datasetVectors))
+	}
+	for i := range got {
+		for j := range got[i] {
+			if math.Abs(got[i][j]-datasetVectors[i][j]) > 1e-6 {
+				t.Errorf("vector %d: got %v, want %v", i, got[i], datasetVectors[i])
+				break
+			}
+		}
+	}
+}
+
+// npyHeader returns the version 1.0 npy header of an array of the given
+// dtype and shape
+func npyHeader(dtype, shape string) []byte {
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': %s, }", dtype, shape)
+	// Pad so the data starts on a 64-byte boundary
+	for (10+len(header)+1)%64 != 0 {
+		header += " "
+	}
+	header += "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	return buf.Bytes()
+}
+
+// npyFile encodes vectors as a version 1.0 npy array of the given dtype
+func npyFile(vectors [][]float64, dtype string) []byte {
+	buf := bytes.NewBuffer(npyHeader(dtype, fmt.Sprintf("(%d, %d)", len(vectors), len(vectors[0]))))
+	for _, v := range vectors {
+		for _, x := range v {
+			if dtype == "<f4" {
+				binary.Write(buf, binary.LittleEndian, float32(x))
+			} else {
+				binary.Write(buf, binary.LittleEndian, x)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDatasetReaders(t *testing.T) {
+	t.Run("fvecs", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := dataset.WriteFvecs(&buf, datasetVectors); err != nil {
+			t.Fatalf("WriteFvecs failed: %v", err)
+		}
+		got, err := dataset.ReadFvecs(&buf)
+		if err != nil {
+			t.Fatalf("ReadFvecs failed: %v", err)
+		}
+		checkVectors(t, got)
+	})
+
+	t.Run("fvecs truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		dataset.WriteFvecs(&buf, datasetVectors)
+		if _, err := dataset.ReadFvecs(bytes.NewReader(buf.Bytes()[:buf.Len()-2])); err == nil {
+			t.Error("expected error for truncated file")
+		}
+	})
+
+	for _, dtype := range []string{"<f4", "<f8"} {
+		t.Run("npy "+dtype, func(t *testing.T) {
+			got, err := dataset.ReadNpy(bytes.NewReader(npyFile(datasetVectors, dtype)))
+			if err != nil {
+				t.Fatalf("ReadNpy failed: %v", err)
+			}
+			checkVectors(t, got)
+		})
+	}
+
+	t.Run("npy unsupported dtype", func(t *testing.T) {
+		if _, err := dataset.ReadNpy(bytes.NewReader(npyFile(datasetVectors, "<i4"))); err == nil {
+			t.Error("expected error for integer dtype")
+		}
+	})
+
+	t.Run("npy bad shape", func(t *testing.T) {
+		// A shape far larger than the data must fail when the data runs
+		// out, not allocate for the whole array first
+		for _, shape := range []string{"(1000000000000, 1000000000)", "(2, 1000000000000)", "(3, 3)", "(99999999999999999999, 3)"} {
+			data := append(npyHeader("<f8", shape), make([]byte, 64)...)
+			if _, err := dataset.ReadNpy(bytes.NewReader(data)); err == nil {
+				t.Errorf("shape %s with 8 values read without error", shape)
+			}
+		}
+	})
+
+	t.Run("fvecs bad dimension", func(t *testing.T) {
+		data := binary.LittleEndian.AppendUint32(nil, math.MaxInt32)
+		if _, err := dataset.ReadFvecs(bytes.NewReader(append(data, make([]byte, 16)...))); err == nil {
+			t.Error("dimension larger than the data read without error")
+		}
+	})
+
+	t.Run("csv with header", func(t *testing.T) {
+		got, err := dataset.ReadCSV(strings.NewReader("x,y,z\n1,2,3\n-0.5, 0.25, 8\n"))
+		if err != nil {
+			t.Fatalf("ReadCSV failed: %v", err)
+		}
+		checkVectors(t, got)
+	})
+
+	t.Run("csv ragged", func(t *testing.T) {
+		if _, err := dataset.ReadCSV(strings.NewReader("1,2,3\n4,5\n")); err == nil {
+			t.Error("expected error for rows of different length")
+		}
+	})
+}
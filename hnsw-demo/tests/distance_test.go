@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestMetricRegistry(t *testing.T) {
+	// Built-in metrics are registered by name
+	for _, name := range []string{distance.Euclidean, distance.Manhattan, distance.Cosine, distance.DotProduct} {
+		if _, err := distance.Lookup(name); err != nil {
+			t.Errorf("built-in metric %s not registered: %v", name, err)
+		}
+	}
+
+	m, _ := distance.Lookup(distance.Euclidean)
+	if !m.Properties.IsMetric {
+		t.Error("euclidean should be flagged as a true metric")
+	}
+
+	// Custom metrics can be registered once and used by the index
+	chebyshevLike := func(a, b []float64) float64 {
+		maxDiff := 0.0
+		for i := range a {
+			if d := a[i] - b[i]; d > maxDiff {
+				maxDiff = d
+			} else if -d > maxDiff {
+				maxDiff = -d
+			}
+		}
+		return maxDiff
+	}
+	// The registry is global, so only register on the first run
+	if _, err := distance.Lookup("test-max-abs"); err != nil {
+		if err := distance.Register("test-max-abs", chebyshevLike, distance.Properties{IsMetric: true}); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+	if err := distance.Register("test-max-abs", chebyshevLike, distance.Properties{}); err == nil {
+		t.Error("expected error when registering a name twice")
+	}
+	if err := distance.Register("", chebyshevLike, distance.Properties{}); err == nil {
+		t.Error("expected error for empty name")
+	}
+	if err := distance.Register("test-nil", nil, distance.Properties{}); err == nil {
+		t.Error("expected error for nil function")
+	}
+
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), "test-max-abs")
+	if err != nil {
+		t.Fatalf("Failed to create HNSW with custom metric: %v", err)
+	}
+	if hnsw.Metric() != "test-max-abs" {
+		t.Errorf("got metric %s, want test-max-abs", hnsw.Metric())
+	}
+
+	if _, err := algorithm.New(config.NewDefaultConfig(), "no-such-metric"); err == nil {
+		t.Error("expected error for unregistered metric")
+	}
+}
+
+func TestRequiresNormalization(t *testing.T) {
+	if _, err := distance.Lookup("test-unit-dot"); err != nil {
+		err := distance.Register("test-unit-dot", func(a, b []float64) float64 {
+			return 1 + distance.DotProductDistance(a, b)
+		}, distance.Properties{RequiresNormalization: true})
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), "test-unit-dot")
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	if err := hnsw.Insert(1, []float64{3, 4}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	// Distances use the normalized copy: an unnormalized query in the same
+	// direction is at distance 1 - 1
+	_, distances := hnsw.KNNSearchWithDistances([]float64{6, 8}, 1, 10)
+	if len(distances) != 1 || math.Abs(distances[0]) > 1e-12 {
+		t.Errorf("expected distance 0 to the normalized vector, got %v", distances)
+	}
+
+	// Get returns the vector as inserted
+	record, _ := hnsw.Get(1)
+	if record.Vector[0] != 3 || record.Vector[1] != 4 {
+		t.Errorf("expected the inserted vector, got %v", record.Vector)
+	}
+}
+
+// vectorPair is a pair of equal-length vectors generated for property tests
+type vectorPair struct {
+	A, B []float64
+}
+
+// randomPairs returns a quick.Config generating vector pairs. With binary
+// set, elements are 0 or 1
+func randomPairs(binary bool) *quick.Config {
+	return &quick.Config{
+		MaxCount: 500,
+		Values: func(values []reflect.Value, rng *rand.Rand) {
+			dim := 1 + rng.Intn(16)
+			pair := vectorPair{A: make([]float64, dim), B: make([]float64, dim)}
+			for i := 0; i < dim; i++ {
+				if binary {
+					pair.A[i] = float64(rng.Intn(2))
+					pair.B[i] = float64(rng.Intn(2))
+				} else {
+					pair.A[i] = rng.NormFloat64() * 10
+					pair.B[i] = rng.NormFloat64() * 10
+				}
+			}
+			values[0] = reflect.ValueOf(pair)
+		},
+	}
+}
+
+func TestMetricProperties(t *testing.T) {
+	tests := []struct {
+		name   string
+		binary bool
+	}{
+		{distance.Euclidean, false},
+		{distance.Manhattan, false},
+		{distance.Chebyshev, false},
+		{distance.MinkowskiName(3), false},
+		{distance.MinkowskiName(1.5), false},
+		{distance.Canberra, false},
+		{distance.Hamming, true},
+		{distance.Jaccard, true},
+		{distance.Tanimoto, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := distance.Lookup(tt.name)
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			if !m.Properties.IsMetric {
+				t.Errorf("%s should be flagged as a true metric", tt.name)
+			}
+
+			symmetric := func(p vectorPair) bool {
+				return math.Abs(m.Func(p.A, p.B)-m.Func(p.B, p.A)) <= 1e-9
+			}
+			if err := quick.Check(symmetric, randomPairs(tt.binary)); err != nil {
+				t.Errorf("not symmetric: %v", err)
+			}
+
+			nonNegative := func(p vectorPair) bool {
+				return m.Func(p.A, p.B) >= 0 && m.Func(p.A, p.A) <= 1e-9
+			}
+			if err := quick.Check(nonNegative, randomPairs(tt.binary)); err != nil {
+				t.Errorf("negative distance or non-zero self distance: %v", err)
+			}
+		})
+	}
+}
+
+func TestAdditionalMetrics(t *testing.T) {
+	a := []float64{1, 0, 1, 1}
+	b := []float64{1, 1, 0, 1}
+
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{distance.Chebyshev, 1},
+		{distance.MinkowskiName(1), 2},
+		{distance.MinkowskiName(2), math.Sqrt(2)},
+		{distance.Hamming, 2},
+		{distance.Jaccard, 0.5},
+		{distance.Canberra, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := distance.GetDistanceFunction(tt.name)
+			if err != nil {
+				t.Fatalf("GetDistanceFunction failed: %v", err)
+			}
+			if got := fn(a, b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+
+			// Each metric can back an index
+			if _, err := algorithm.New(config.NewDefaultConfig(), tt.name); err != nil {
+				t.Errorf("algorithm.New failed: %v", err)
+			}
+		})
+	}
+
+	for _, name := range []string{"minkowski", "minkowski:x", "minkowski:-1", "minkowski:NaN", "minkowski:Inf"} {
+		if _, err := distance.Lookup(name); err == nil {
+			t.Errorf("expected error for metric %q", name)
+		}
+	}
+}
+
+func TestInternalDistancesMatchMetric(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := make(map[int][]float64)
+	for i := 1; i <= 300; i++ {
+		v := make([]float64, 8)
+		for j := range v {
+			v[j] = rng.NormFloat64()
+		}
+		vectors[i] = v
+	}
+	query := make([]float64, 8)
+	for j := range query {
+		query[j] = rng.NormFloat64()
+	}
+
+	build := func(metric string) *algorithm.HNSW {
+		hnsw, err := algorithm.New(config.NewDefaultConfig(), metric)
+		if err != nil {
+			t.Fatalf("Failed to create HNSW: %v", err)
+		}
+		for id := 1; id <= len(vectors); id++ {
+			if err := hnsw.Insert(id, vectors[id]); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+		}
+		return hnsw
+	}
+
+	t.Run("Euclidean reports true distances", func(t *testing.T) {
+		hnsw := build(distance.Euclidean)
+		ids, distances := hnsw.KNNSearchWithDistances(query, 10, 100)
+		for i, id := range ids {
+			if want := distance.EuclideanDistance(query, vectors[id]); math.Abs(distances[i]-want) > 1e-12 {
+				t.Errorf("id %d: got distance %f, want %f", id, distances[i], want)
+			}
+		}
+	})
+
+	t.Run("Normalized cosine matches cosine", func(t *testing.T) {
+		cosine := build(distance.Cosine)
+		normalized := build(distance.NormalizedCosine)
+		// Use exhaustive ef so both return the exact top K
+		wantIDs, wantDistances := cosine.KNNSearchWithDistances(query, 10, len(vectors))
+		gotIDs, gotDistances := normalized.KNNSearchWithDistances(query, 10, len(vectors))
+		if len(gotIDs) != len(wantIDs) {
+			t.Fatalf("got %d results, want %d", len(gotIDs), len(wantIDs))
+		}
+		for i := range wantIDs {
+			if gotIDs[i] != wantIDs[i] || math.Abs(gotDistances[i]-wantDistances[i]) > 1e-9 {
+				t.Errorf("result %d: got (%d, %f), want (%d, %f)",
+					i, gotIDs[i], gotDistances[i], wantIDs[i], wantDistances[i])
+			}
+		}
+
+		// Stored vectors come back as inserted, not normalized
+		record, err := normalized.Get(1)
+		if err != nil || !reflect.DeepEqual(record.Vector, vectors[1]) {
+			t.Errorf("Get: got %v, %v, want the inserted %v", record.Vector, err, vectors[1])
+		}
+		results, err := normalized.Search(context.Background(), query, algorithm.SearchOptions{K: 1, IncludeVectors: true})
+		if err != nil || len(results) != 1 || !reflect.DeepEqual(results[0].Vector, vectors[results[0].ID]) {
+			t.Errorf("IncludeVectors: got %+v, %v, want the inserted vector", results, err)
+		}
+	})
+
+	t.Run("Zero vectors are infinitely far under both", func(t *testing.T) {
+		zero := make([]float64, 8)
+		for _, pair := range [][2][]float64{{zero, query}, {query, zero}, {zero, zero}} {
+			want := distance.CosineDistance(pair[0], pair[1])
+			got := distance.NormalizedCosineDistance(distance.NormalizeVector(pair[0]), distance.NormalizeVector(pair[1]))
+			if !math.IsInf(want, 1) || got != want {
+				t.Errorf("got %f, want %f", got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestHybridSearch(t *testing.T) {
+	hybrid, err := algorithm.NewHybrid(config.NewDefaultConfig(), distance.Euclidean, distance.DotProduct)
+	if err != nil {
+		t.Fatalf("Failed to create hybrid index: %v", err)
+	}
+
+	// Dense vectors on a line, one keyword per element
+	for id := 1; id <= 20; id++ {
+		sv, _ := sparse.FromMap(map[int]float64{id: 1})
+		if err := hybrid.Insert(id, []float64{float64(id), 0}, sv); err != nil {
+			t.Fatalf("Failed to insert %d: %v", id, err)
+		}
+	}
+	if err := hybrid.Insert(1, []float64{0, 0}, sparse.Vector{}); err == nil {
+		t.Error("expected error for duplicate id")
+	}
+
+	// A bad sparse vector keeps the element out of the dense index too
+	invalid := sparse.Vector{Indices: []int{2, 1}, Values: []float64{1, 1}}
+	if err := hybrid.Insert(21, []float64{21, 0}, invalid); err == nil {
+		t.Error("expected error for an invalid sparse vector")
+	}
+	if _, err := hybrid.Dense().Get(21); err == nil {
+		t.Error("element with an invalid sparse vector was added to the dense index")
+	}
+
+	// Dense query favours 1, keyword query matches only 10
+	dq := []float64{1, 0}
+	sq, _ := sparse.FromMap(map[int]float64{10: 1})
+	ctx := context.Background()
+
+	for _, fusion := range []algorithm.Fusion{algorithm.FusionRRF, algorithm.FusionWeightedSum} {
+		results, err := hybrid.Search(ctx, dq, sq, algorithm.HybridOptions{K: 5, Fusion: fusion})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 5 {
+			t.Fatalf("got %d results, want 5", len(results))
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].Score > results[i-1].Score {
+				t.Error("results not ranked by score")
+			}
+		}
+		if !results[0].Dense.Found || results[0].Dense.Rank != 1 || results[0].ID != 1 {
+			t.Errorf("fusion %d: expected dense top hit first, got %+v", fusion, results[0])
+		}
+
+		found := false
+		for _, r := range results {
+			if r.ID == 10 {
+				found = r.Sparse.Found && r.Sparse.Rank == 1
+			}
+		}
+		if !found {
+			t.Errorf("fusion %d: keyword match missing from fused results", fusion)
+		}
+	}
+
+	// Weighting the sparse source puts the keyword match first
+	results, err := hybrid.Search(ctx, dq, sq, algorithm.HybridOptions{
+		K:            3,
+		Fusion:       algorithm.FusionWeightedSum,
+		DenseWeight:  0.2,
+		SparseWeight: 1,
+	})
+	if err != nil || results[0].ID != 10 {
+		t.Errorf("expected keyword match first, got %+v, %v", results, err)
+	}
+
+	// A dense-only query ranks like the dense index
+	results, err = hybrid.Search(ctx, dq, sparse.Vector{}, algorithm.HybridOptions{K: 3})
+	if err != nil || len(results) != 3 || results[0].ID != 1 || results[0].Sparse.Found {
+		t.Errorf("unexpected dense-only results %+v, %v", results, err)
+	}
+}
+
+func TestHybridInsertRace(t *testing.T) {
+	hybrid, err := algorithm.NewHybrid(config.NewDefaultConfig(), distance.Euclidean, distance.DotProduct)
+	if err != nil {
+		t.Fatalf("Failed to create hybrid index: %v", err)
+	}
+
+	// Race each hybrid insert against a sparse-only insert of the same id.
+	// Whichever way it goes, a failed hybrid insert leaves nothing behind
+	// in the dense index and is not counted as a deletion
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4)) // interleave even on one CPU
+	failed := make(map[int]bool)
+	for id := 0; id < 300; id++ {
+		sv, _ := sparse.FromMap(map[int]float64{id: 1})
+		start := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			<-start
+			hybrid.Sparse().InsertSparse(id, sv)
+		}()
+		close(start)
+		if err := hybrid.Insert(id, []float64{float64(id), 0}, sv); err != nil {
+			failed[id] = true
+		}
+		<-done
+	}
+
+	for id := 0; id < 300; id++ {
+		if _, err := hybrid.Dense().Get(id); (err == nil) == failed[id] {
+			t.Errorf("id %d: hybrid insert failed %v, in dense index %v", id, failed[id], err == nil)
+		}
+		if _, err := hybrid.Sparse().Get(id); err != nil {
+			t.Errorf("id %d missing from the sparse index", id)
+		}
+	}
+	if deleted := hybrid.Dense().Stats().Deleted; deleted != 0 {
+		t.Errorf("undone inserts counted as %d deletions", deleted)
+	}
+}
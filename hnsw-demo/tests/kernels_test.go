@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+)
+
+// Reference implementations the kernels must agree with
+func naiveSquaredL2(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+func naiveDot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func naiveCosine(a, b []float64) float64 {
+	return 1 - naiveDot(a, b)/(math.Sqrt(naiveDot(a, a))*math.Sqrt(naiveDot(b, b)))
+}
+
+func randomVector(rng *rand.Rand, dim int) []float64 {
+	v := make([]float64, dim)
+	for i := range v {
+		v[i] = rng.NormFloat64()
+	}
+	return v
+}
+
+func toFloat32(v []float64) []float32 {
+	result := make([]float32, len(v))
+	for i, val := range v {
+		result[i] = float32(val)
+	}
+	return result
+}
+
+// closeTo reports whether got is within a relative tolerance of want
+func closeTo(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance*math.Max(1, math.Abs(want))
+}
+
+func TestKernelEquivalence(t *testing.T) {
+	t.Logf("kernel implementation: %s", distance.KernelImplementation())
+	rng := rand.New(rand.NewSource(4))
+
+	// Cover every remainder of the unrolled and vectorized loops
+	for dim := 1; dim <= 70; dim++ {
+		for trial := 0; trial < 5; trial++ {
+			a, b := randomVector(rng, dim), randomVector(rng, dim)
+
+			if got, want := distance.SquaredEuclideanDistance(a, b), naiveSquaredL2(a, b); !closeTo(got, want, 1e-12) {
+				t.Errorf("dim %d: squared L2 got %v, want %v", dim, got, want)
+			}
+			if got, want := distance.EuclideanDistance(a, b), math.Sqrt(naiveSquaredL2(a, b)); !closeTo(got, want, 1e-12) {
+				t.Errorf("dim %d: L2 got %v, want %v", dim, got, want)
+			}
+			if got, want := distance.DotProductDistance(a, b), -naiveDot(a, b); !closeTo(got, want, 1e-12) {
+				t.Errorf("dim %d: dot got %v, want %v", dim, got, want)
+			}
+			if got, want := distance.CosineDistance(a, b), naiveCosine(a, b); !closeTo(got, want, 1e-12) {
+				t.Errorf("dim %d: cosine got %v, want %v", dim, got, want)
+			}
+
+			a32, b32 := toFloat32(a), toFloat32(b)
+			if got, want := float64(distance.SquaredEuclideanFloat32(a32, b32)), naiveSquaredL2(a, b); !closeTo(got, want, 1e-4) {
+				t.Errorf("dim %d: float32 squared L2 got %v, want %v", dim, got, want)
+			}
+			if got, want := float64(distance.EuclideanDistanceFloat32(a32, b32)), math.Sqrt(naiveSquaredL2(a, b)); !closeTo(got, want, 1e-4) {
+				t.Errorf("dim %d: float32 L2 got %v, want %v", dim, got, want)
+			}
+			if got, want := float64(distance.InnerProductFloat32(a32, b32)), naiveDot(a, b); !closeTo(got, want, 1e-4) {
+				t.Errorf("dim %d: float32 dot got %v, want %v", dim, got, want)
+			}
+			if got, want := float64(distance.CosineDistanceFloat32(a32, b32)), naiveCosine(a, b); !closeTo(got, want, 1e-4) {
+				t.Errorf("dim %d: float32 cosine got %v, want %v", dim, got, want)
+			}
+		}
+	}
+
+	// Mismatched and empty inputs
+	if !math.IsInf(distance.EuclideanDistance([]float64{1}, []float64{1, 2}), 1) {
+		t.Error("expected +Inf for mismatched dimensions")
+	}
+	if !math.IsInf(float64(distance.SquaredEuclideanFloat32([]float32{1}, nil)), 1) {
+		t.Error("expected +Inf for mismatched float32 dimensions")
+	}
+	if distance.SquaredEuclideanDistance(nil, nil) != 0 || distance.InnerProductFloat32(nil, nil) != 0 {
+		t.Error("expected zero for empty vectors")
+	}
+}
+
+func BenchmarkKernels(b *testing.B) {
+	rng := rand.New(rand.NewSource(5))
+	for _, dim := range []int{16, 128, 768} {
+		x, y := randomVector(rng, dim), randomVector(rng, dim)
+		x32, y32 := toFloat32(x), toFloat32(y)
+
+		b.Run(fmt.Sprintf("SquaredL2/naive/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				naiveSquaredL2(x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("SquaredL2/float64/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distance.SquaredEuclideanDistance(x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("SquaredL2/float32/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distance.SquaredEuclideanFloat32(x32, y32)
+			}
+		})
+		b.Run(fmt.Sprintf("Dot/naive/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				naiveDot(x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("Dot/float64/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distance.DotProductDistance(x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("Dot/float32/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distance.InnerProductFloat32(x32, y32)
+			}
+		})
+		b.Run(fmt.Sprintf("Cosine/float64/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distance.CosineDistance(x, y)
+			}
+		})
+		b.Run(fmt.Sprintf("Cosine/float32/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				distance.CosineDistanceFloat32(x32, y32)
+			}
+		})
+	}
+}
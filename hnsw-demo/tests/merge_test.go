@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// mergeIndex builds an index over n random vectors numbered from first
+func mergeIndex(t *testing.T, rng *rand.Rand, metric string, first, n, dim int) *algorithm.HNSW {
+	t.Helper()
+
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM, cfg.EfConstruction = 8, 16, 60
+	cfg.Seed = int64(first + 1)
+	hnsw, err := algorithm.New(cfg, metric)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if err := hnsw.Insert(first+i, randomVector(rng, dim)); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", first+i, err)
+		}
+	}
+	return hnsw
+}
+
+func TestMerge(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	a := mergeIndex(t, rng, distance.Euclidean, 0, 1000, 8)
+	b := mergeIndex(t, rng, distance.Euclidean, 1000, 700, 8)
+	statsA, statsB := a.Stats(), b.Stats()
+
+	merged, err := algorithm.Merge(a, b)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if merged.Len() != 1700 || merged.Dimension() != 8 {
+		t.Fatalf("got %d elements of dimension %d", merged.Len(), merged.Dimension())
+	}
+	if report := merged.Verify(); !report.OK() {
+		t.Errorf("merged graph is invalid:\n%s", report)
+	}
+	if a.Stats().Levels[0].MeanDegree != statsA.Levels[0].MeanDegree || b.Stats().Levels[0].MeanDegree != statsB.Levels[0].MeanDegree {
+		t.Error("Merge changed its inputs")
+	}
+
+	// Queries find neighbors from both sides
+	queries := make([][]float64, 50)
+	for i := range queries {
+		queries[i] = randomVector(rng, 8)
+	}
+	if recall := bulkRecall(t, merged, queries); recall < 0.9 {
+		t.Errorf("recall@10 %.3f, want at least 0.9", recall)
+	}
+
+	// Many bottom layer edges cross between the two inputs
+	filename := filepath.Join(t.TempDir(), "merged.hnsw")
+	if err := merged.Save(filename, ""); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	data, err := storage.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	crossEdges := 0
+	for id, n := range data.Nodes {
+		for _, neighborID := range n.Neighbors[0] {
+			if (id < 1000) != (neighborID < 1000) {
+				crossEdges++
+			}
+		}
+	}
+	if crossEdges < 1000 {
+		t.Errorf("only %d edges between the merged indexes", crossEdges)
+	}
+
+	// The merged index stays mutable
+	if err := merged.Insert(5000, randomVector(rng, 8)); err != nil {
+		t.Errorf("Insert after Merge failed: %v", err)
+	}
+	if err := merged.Delete(1000); err != nil {
+		t.Errorf("Delete after Merge failed: %v", err)
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	a := mergeIndex(t, rng, distance.Euclidean, 0, 50, 4)
+
+	if _, err := algorithm.Merge(a, mergeIndex(t, rng, distance.Euclidean, 40, 20, 4)); !errors.Is(err, algorithm.ErrNodeExists) {
+		t.Errorf("conflicting ids: got %v, want ErrNodeExists", err)
+	}
+	if _, err := algorithm.Merge(a, mergeIndex(t, rng, distance.Euclidean, 100, 20, 3)); !errors.Is(err, algorithm.ErrDimensionMismatch) {
+		t.Errorf("mismatched dimension: got %v, want ErrDimensionMismatch", err)
+	}
+	if _, err := algorithm.Merge(a, mergeIndex(t, rng, distance.Cosine, 100, 20, 4)); err == nil {
+		t.Error("merging indexes with different metrics succeeded")
+	}
+	if _, err := algorithm.Merge(a, a); err == nil {
+		t.Error("merging an index with itself succeeded")
+	}
+
+	// Merging with an empty index copies the other one
+	empty, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	merged, err := algorithm.Merge(empty, a)
+	if err != nil {
+		t.Fatalf("Merge with an empty index failed: %v", err)
+	}
+	if merged.Len() != a.Len() || merged.Dimension() != 4 {
+		t.Errorf("got %d elements of dimension %d, want %d of 4", merged.Len(), merged.Dimension(), a.Len())
+	}
+	if report := merged.Verify(); !report.OK() {
+		t.Errorf("copied graph is invalid:\n%s", report)
+	}
+}
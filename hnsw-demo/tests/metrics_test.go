@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	index := metrics.New(hnsw)
+
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		if err := index.Insert(i, []float64{rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+	if err := index.Insert(0, []float64{0, 0}); err == nil {
+		t.Fatal("duplicate insert succeeded")
+	}
+	if err := index.Delete(3); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if err := index.Delete(3); err == nil {
+		t.Fatal("deleting a missing id succeeded")
+	}
+
+	var computations int
+	for i := 0; i < 10; i++ {
+		_, stats, err := index.SearchWithStats(context.Background(), []float64{rng.Float64(), rng.Float64()}, algorithm.SearchOptions{K: 5})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		computations += stats.DistanceComputations
+	}
+	if _, err := index.Search(context.Background(), []float64{1, 2, 3}, algorithm.SearchOptions{K: 5}); err == nil {
+		t.Fatal("search with the wrong dimension succeeded")
+	}
+
+	ts := httptest.NewServer(index.Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("got content type %q, want %q", ct, metrics.ContentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	for _, line := range []string{
+		"# TYPE hnsw_operations_total counter",
+		`hnsw_operations_total{op="insert"} 51`,
+		`hnsw_operations_total{op="delete"} 2`,
+		`hnsw_operations_total{op="search"} 11`,
+		`hnsw_operation_errors_total{op="insert"} 1`,
+		`hnsw_operation_errors_total{op="delete"} 1`,
+		`hnsw_operation_errors_total{op="search"} 1`,
+		"# TYPE hnsw_operation_duration_seconds histogram",
+		`hnsw_operation_duration_seconds_bucket{op="search",le="+Inf"} 11`,
+		`hnsw_operation_duration_seconds_count{op="insert"} 51`,
+		"hnsw_search_distance_computations_total " + strconv.Itoa(computations),
+		"hnsw_nodes 49",
+	} {
+		if !containsLine(text, line) {
+			t.Errorf("missing line %q in:\n%s", line, text)
+		}
+	}
+}
+
+func TestMetricsBuckets(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	for _, buckets := range [][]float64{
+		{0.1, 0.01},
+		{0.1, 0.1},
+		{0.1, math.NaN()},
+		{0.1, math.Inf(1)},
+		{math.Inf(-1), 0.1},
+	} {
+		if _, err := metrics.NewWithBuckets(hnsw, buckets); err == nil {
+			t.Errorf("NewWithBuckets(%v) succeeded", buckets)
+		}
+	}
+
+	buckets := []float64{0.5, 60}
+	index, err := metrics.NewWithBuckets(hnsw, buckets)
+	if err != nil {
+		t.Fatalf("NewWithBuckets failed: %v", err)
+	}
+	buckets[0] = 100 // the index keeps its own copy
+	if err := index.Insert(1, []float64{0, 0}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	var text strings.Builder
+	if _, err := index.WriteTo(&text); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	for _, line := range []string{
+		`hnsw_operation_duration_seconds_bucket{op="insert",le="0.5"} 1`,
+		`hnsw_operation_duration_seconds_bucket{op="insert",le="60"} 1`,
+		`hnsw_operation_duration_seconds_bucket{op="insert",le="+Inf"} 1`,
+	} {
+		if !containsLine(text.String(), line) {
+			t.Errorf("missing line %q in:\n%s", line, text.String())
+		}
+	}
+}
+
+func containsLine(text, line string) bool {
+	for _, l := range strings.Split(text, "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestDelete(t *testing.T) {
+	for _, delay := range []bool{false, true} {
+		t.Run(fmt.Sprintf("DelayRebuild=%v", delay), func(t *testing.T) {
+			cfg := config.NewDefaultConfig()
+			cfg.DelayRebuild = delay
+			hnsw, err := algorithm.New(cfg, distance.Euclidean)
+			if err != nil {
+				t.Fatalf("Failed to create HNSW: %v", err)
+			}
+
+			rng := rand.New(rand.NewSource(3))
+			for i := 1; i <= 300; i++ {
+				if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64()}); err != nil {
+					t.Fatalf("Failed to insert vector %d: %v", i, err)
+				}
+			}
+
+			// Delete every other element, including whatever is the entry point
+			for i := 2; i <= 300; i += 2 {
+				if err := hnsw.Delete(i); err != nil {
+					t.Fatalf("Failed to delete %d: %v", i, err)
+				}
+			}
+			if err := hnsw.Delete(2); !errors.Is(err, algorithm.ErrNodeNotFound) {
+				t.Errorf("got %v, want ErrNodeNotFound", err)
+			}
+			if err := hnsw.DeleteBatch([]int{1, 2, 3}); !errors.Is(err, algorithm.ErrNodeNotFound) {
+				t.Errorf("got %v, want ErrNodeNotFound", err)
+			}
+			if hnsw.Len() != 150 {
+				t.Errorf("failed batch delete left %d elements, want 150", hnsw.Len())
+			}
+
+			results, err := hnsw.Search(context.Background(), []float64{0.5, 0.5}, algorithm.SearchOptions{K: 20, Ef: 100})
+			if err != nil || len(results) != 20 {
+				t.Fatalf("got %d results and error %v, want 20 results", len(results), err)
+			}
+			for _, result := range results {
+				if result.ID%2 == 0 {
+					t.Errorf("deleted element %d returned", result.ID)
+				}
+			}
+
+			// Deleting everything in one batch leaves a usable empty index
+			var rest []int
+			for i := 1; i <= 300; i += 2 {
+				rest = append(rest, i)
+			}
+			if err := hnsw.DeleteBatch(rest); err != nil {
+				t.Fatalf("DeleteBatch failed: %v", err)
+			}
+			if hnsw.Len() != 0 {
+				t.Fatalf("got %d elements after deleting all, want 0", hnsw.Len())
+			}
+			if err := hnsw.Insert(1, []float64{1, 1}); err != nil {
+				t.Fatalf("Failed to insert into emptied index: %v", err)
+			}
+			ids := hnsw.KNNSearch([]float64{0, 0}, 1, 10)
+			if len(ids) != 1 || ids[0] != 1 {
+				t.Errorf("got %v, want [1]", ids)
+			}
+		})
+	}
+}
+
+func TestMultiVector(t *testing.T) {
+	mv, err := algorithm.NewMultiVector(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create multi-vector index: %v", err)
+	}
+
+	// "near" has one chunk next to the query, "many" has several moderately
+	// close chunks, the rest are far away
+	if err := mv.Add("near", []float64{0.1, 0}, []float64{9, 9}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := mv.Add("many", []float64{0.5, 0}, []float64{0, 0.5}, []float64{-0.5, 0}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := mv.Add(fmt.Sprintf("far-%d", i), []float64{float64(10 + i), 10}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := mv.Add("bad", []float64{1, 1}, []float64{1, 1, 1}); err == nil {
+		t.Error("expected error for dimension mismatch")
+	}
+	if mv.Len() != 22 || mv.Index().Len() != 25 {
+		t.Errorf("got %d documents and %d vectors, want 22 and 25", mv.Len(), mv.Index().Len())
+	}
+	if deleted := mv.Index().Stats().Deleted; deleted != 0 {
+		t.Errorf("undoing a failed Add counted %d deletions", deleted)
+	}
+
+	ctx := context.Background()
+	query := []float64{0, 0}
+
+	results, err := mv.Search(ctx, query, algorithm.MultiVectorOptions{K: 2})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("got %d results and error %v, want 2 results", len(results), err)
+	}
+	if results[0].Key != "near" || results[1].Key != "many" {
+		t.Errorf("max-sim: got %s, %s, want near, many", results[0].Key, results[1].Key)
+	}
+	if len(results[1].Chunks) != 3 || results[1].Chunks[0].Distance > results[1].Chunks[1].Distance {
+		t.Errorf("unexpected chunks %+v", results[1].Chunks)
+	}
+
+	results, err = mv.Search(ctx, query, algorithm.MultiVectorOptions{K: 2, Aggregation: algorithm.AggregateSumTopN, TopN: 3})
+	if err != nil || results[0].Key != "many" {
+		t.Errorf("sum of top-n: got %+v, %v, want many first", results, err)
+	}
+
+	// Deleting a document removes all of its vectors
+	if err := mv.Delete("many"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := mv.Delete("many"); !errors.Is(err, algorithm.ErrDocumentNotFound) {
+		t.Errorf("got %v, want ErrDocumentNotFound", err)
+	}
+	if mv.Index().Len() != 22 {
+		t.Errorf("got %d vectors after delete, want 22", mv.Index().Len())
+	}
+	results, _ = mv.Search(ctx, query, algorithm.MultiVectorOptions{K: 3})
+	for _, result := range results {
+		if result.Key == "many" {
+			t.Error("deleted document returned")
+		}
+	}
+
+	// A chunk removed behind the document's back fails the delete without
+	// removing the other chunks
+	if err := mv.Index().Delete(1); err != nil {
+		t.Fatalf("Failed to delete chunk 1: %v", err)
+	}
+	if err := mv.Delete("near"); !errors.Is(err, algorithm.ErrNodeNotFound) {
+		t.Errorf("got %v, want ErrNodeNotFound", err)
+	}
+	if _, err := mv.Index().Get(0); err != nil || mv.Len() != 21 {
+		t.Errorf("failed delete changed the index: %v, %d documents", err, mv.Len())
+	}
+}
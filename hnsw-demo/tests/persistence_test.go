@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Manhattan)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	for i := 1; i <= 30; i++ {
+		attrs := map[string]string{"parity": []string{"even", "odd"}[i%2]}
+		if err := hnsw.InsertWithAttributes(i, []float64{float64(i), float64(i % 7)}, attrs); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// gob is chosen for any extension but .json
+	for _, name := range []string{"index.hnsw", "index.json"} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), name)
+			if err := hnsw.Save(filename, "test index"); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			info, err := storage.GetIndexInfo(filename)
+			if err != nil {
+				t.Fatalf("GetIndexInfo failed: %v", err)
+			}
+			if info.Metric != distance.Manhattan || info.NodesCount != 30 {
+				t.Errorf("unexpected metadata: %+v", info)
+			}
+
+			loaded, err := algorithm.Load(filename)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if loaded.Metric() != distance.Manhattan {
+				t.Errorf("got metric %s, want %s", loaded.Metric(), distance.Manhattan)
+			}
+
+			query := []float64{10.2, 3.1}
+			opts := algorithm.SearchOptions{K: 5, Ef: 30, IncludeAttributes: true}
+			want, _ := hnsw.Search(context.Background(), query, opts)
+			got, err := loaded.Search(context.Background(), query, opts)
+			if err != nil {
+				t.Fatalf("Search on loaded index failed: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d results, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i].ID != want[i].ID || got[i].Attributes["parity"] != want[i].Attributes["parity"] {
+					t.Errorf("result %d: got %+v, want %+v", i, got[i], want[i])
+				}
+			}
+
+			// The loaded index remains mutable
+			if err := loaded.Insert(31, []float64{31, 3}); err != nil {
+				t.Errorf("Insert into loaded index failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestSaveKeepsOldFileOnFailure(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		if err := hnsw.Insert(i, []float64{float64(i), 0}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "index.hnsw")
+	if err := hnsw.Save(filename, "test index"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := storage.Load(filename)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	data.Nodes = nil
+	if err := storage.SaveAs(filename, data, storage.Format("no-such-format")); err == nil {
+		t.Fatal("SaveAs with an unknown format succeeded")
+	}
+	if info, err := storage.GetIndexInfo(filename); err != nil || info.NodesCount != 10 {
+		t.Errorf("failed save changed the previous file: %+v, %v", info, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files after a failed save, want only the index", len(entries))
+	}
+}
+
+func TestLoadDetectsFormat(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		if err := hnsw.Insert(i, []float64{float64(i), 1}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Extensions that disagree with the format written
+	for _, tc := range []struct {
+		name   string
+		format storage.Format
+	}{
+		{"json-as.hnsw", storage.FormatJSON},
+		{"gob-as.json", storage.FormatGob},
+	} {
+		filename := filepath.Join(t.TempDir(), tc.name)
+		if err := hnsw.SaveAs(filename, "", tc.format); err != nil {
+			t.Fatalf("%s: SaveAs failed: %v", tc.name, err)
+		}
+		if format, err := storage.DetectFormat(filename); err != nil || format != tc.format {
+			t.Errorf("%s: detected %q, %v, want %q", tc.name, format, err, tc.format)
+		}
+		if info, err := storage.GetIndexInfo(filename); err != nil || info.NodesCount != 10 {
+			t.Errorf("%s: GetIndexInfo: %+v, %v", tc.name, info, err)
+		}
+		loaded, err := algorithm.Load(filename)
+		if err != nil {
+			t.Fatalf("%s: Load failed: %v", tc.name, err)
+		}
+		if loaded.Len() != 10 {
+			t.Errorf("%s: loaded %d elements, want 10", tc.name, loaded.Len())
+		}
+	}
+}
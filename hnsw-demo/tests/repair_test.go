@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestRepair(t *testing.T) {
+	// Deleting without reconnection leaves nodes behind that lost all of
+	// their in-edges
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM = 3, 6
+	cfg.DelayRebuild = true
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(8))
+	for i := 0; i < 1000; i++ {
+		if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 1000; i += 2 {
+		if err := hnsw.Delete(i); err != nil {
+			t.Fatalf("Failed to delete %d: %v", i, err)
+		}
+	}
+
+	before := hnsw.Verify().Count(algorithm.ProblemUnreachable)
+	if before == 0 {
+		t.Fatal("expected unreachable nodes after deleting without reconnection")
+	}
+
+	report := hnsw.Repair()
+	t.Logf("repair: %s", report)
+	if report.Unreachable != before {
+		t.Errorf("report counts %d unreachable, Verify counted %d", report.Unreachable, before)
+	}
+	if report.Remaining != 0 || len(report.Reconnected) == 0 {
+		t.Errorf("got %s, want everything reconnected", report)
+	}
+	for _, r := range report.Reconnected {
+		if len(r.InEdges) == 0 {
+			t.Errorf("node %d on level %d reported without in-edges", r.NodeID, r.Level)
+		}
+	}
+	if after := hnsw.Verify(); !after.OK() {
+		t.Errorf("repaired index has problems:\n%s", after)
+	}
+
+	// A repaired graph needs no further work
+	if again := hnsw.Repair(); again.Unreachable != 0 || len(again.Reconnected) != 0 {
+		t.Errorf("second repair: got %s", again)
+	}
+}
+
+func TestRepairInvalidEdges(t *testing.T) {
+	hnsw, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < 200; i++ {
+		if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+
+	// Dangling edges, a self-loop and an entry point below the top level in a
+	// saved file
+	filename := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := hnsw.Save(filename, ""); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	data, err := storage.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	data.Nodes[5].Neighbors[0] = append(data.Nodes[5].Neighbors[0], 5, 1000, 1001)
+	for id, n := range data.Nodes {
+		if n.Level < data.Metadata.MaxLevel {
+			data.EntryPoint = id
+			break
+		}
+	}
+	if err := storage.Save(filename, data); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	corrupted, err := algorithm.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	report := corrupted.Repair()
+	if report.RemovedEdges != 3 || !report.EntryPointMoved {
+		t.Errorf("got %s, want 3 removed edges and a moved entry point", report)
+	}
+	if after := corrupted.Verify(); !after.OK() {
+		t.Errorf("repaired index has problems:\n%s", after)
+	}
+}
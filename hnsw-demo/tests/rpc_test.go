@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/rpc"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/rpc/client"
+)
+
+// startRPC serves index over an in-process listener and returns a client
+func startRPC(t *testing.T, index *algorithm.HNSW) *client.Client {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	rpc.Register(srv, index)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	c, err := client.Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestRPC(t *testing.T) {
+	index, err := algorithm.New(config.NewDefaultConfig(), distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	c := startRPC(t, index)
+	ctx := context.Background()
+
+	if err := c.Insert(ctx, 1, []float64{1, 0}, map[string]string{"lang": "en"}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := c.Insert(ctx, 1, []float64{1, 0}, nil); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("duplicate insert: got %v, want AlreadyExists", err)
+	}
+
+	// Stream the rest, including one element of the wrong dimension
+	var records []client.Record
+	for i := 2; i <= 30; i++ {
+		lang := "en"
+		if i%2 == 0 {
+			lang = "de"
+		}
+		records = append(records, client.Record{ID: i, Vector: []float64{float64(i), 0}, Attributes: map[string]string{"lang": lang}})
+	}
+	records = append(records, client.Record{ID: 99, Vector: []float64{1, 2, 3}})
+	inserted, errs, err := c.BulkInsert(ctx, records)
+	if err != nil {
+		t.Fatalf("BulkInsert failed: %v", err)
+	}
+	if inserted != 29 || len(errs) != 1 || errs[0].ID != 99 || errs[0].Index != 29 {
+		t.Errorf("got %d inserted and errors %+v", inserted, errs)
+	}
+
+	results, stats, err := c.Search(ctx, []float64{0, 0}, algorithm.SearchOptions{K: 3, IncludeVectors: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 3 || results[0].ID != 1 || results[0].Vector[0] != 1 || stats.DistanceComputations == 0 {
+		t.Errorf("unexpected results %+v, stats %+v", results, stats)
+	}
+
+	if _, _, err := c.Search(ctx, []float64{0}, algorithm.SearchOptions{K: 3}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("dimension mismatch: got %v, want InvalidArgument", err)
+	}
+
+	batch, err := c.BatchSearch(ctx, []client.Query{
+		{Vector: []float64{0, 0}, Options: algorithm.SearchOptions{K: 2}, Filter: map[string]string{"lang": "de"}},
+		{Vector: []float64{30, 0}, Options: algorithm.SearchOptions{K: 1}},
+	})
+	if err != nil {
+		t.Fatalf("BatchSearch failed: %v", err)
+	}
+	if len(batch) != 2 || batch[0][0].ID != 2 || batch[0][1].ID != 4 || batch[1][0].ID != 30 {
+		t.Errorf("unexpected batch results %+v", batch)
+	}
+
+	if err := c.Delete(ctx, 1); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if err := c.Delete(ctx, 1); status.Code(err) != codes.NotFound {
+		t.Errorf("second delete: got %v, want NotFound", err)
+	}
+
+	got, err := c.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if got.Count != 29 || got.Dimension != 2 || got.Metric != distance.Euclidean || got.M != 16 {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
@@ -1,6 +1,9 @@
 package tests
 
 import (
+	"context"
+	"errors"
+	"math/rand"
 	"testing"
 
 	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
@@ -45,7 +48,7 @@ func TestKNNSearch(t *testing.T) {
 			k:         2,
 			ef:        10,
 			wantLen:   2,
-			wantFirst: 2, // Closest to (1,1)
+			wantFirst: 1, // 1 and 2 tie at (1,1), the lower id comes first
 		},
 		{
 			name:      "K larger than dataset",
@@ -53,7 +56,7 @@ func TestKNNSearch(t *testing.T) {
 			k:         10,
 			ef:        20,
 			wantLen:   5, // Should return all vectors
-			wantFirst: 2,
+			wantFirst: 1,
 		},
 		{
 			name:      "Single nearest neighbor",
@@ -124,4 +127,187 @@ func TestSearchDimensionMismatch(t *testing.T) {
 	if len(results) != 0 || len(distances) != 0 {
 		t.Error("Expected empty results for dimension mismatch")
 	}
+
+	// The unified search reports the mismatch
+	_, err = hnsw.Search(context.Background(), query, algorithm.SearchOptions{K: 1})
+	if !errors.Is(err, algorithm.ErrDimensionMismatch) {
+		t.Errorf("got error %v, want ErrDimensionMismatch", err)
+	}
+}
+
+func TestRangeSearch(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	// Points on a line at distance 1, 2, ..., 20 from the origin
+	for i := 1; i <= 20; i++ {
+		if err := hnsw.Insert(i, []float64{float64(i), 0}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+
+	// A small ef must still grow to cover the whole radius
+	results, distances := hnsw.RangeSearch([]float64{0, 0}, 7.5, 2)
+	if len(results) != 7 {
+		t.Fatalf("got len %d, want 7", len(results))
+	}
+	for i, dist := range distances {
+		if dist > 7.5 {
+			t.Errorf("result %d at distance %f is outside the radius", results[i], dist)
+		}
+	}
+}
+
+func TestSearchContextCanceled(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 1; i <= 200; i++ {
+		if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	query := []float64{0.5, 0.5}
+	results, err := hnsw.KNNSearchContext(ctx, query, 10, 50)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if len(results) == 0 || len(results) > 10 {
+		t.Errorf("got %d partial results, want between 1 and 10", len(results))
+	}
+
+	if _, err := hnsw.Search(ctx, query, algorithm.SearchOptions{K: 10}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Search: got error %v, want context.Canceled", err)
+	}
+	if _, _, err := hnsw.RangeSearchContext(ctx, query, 0.1, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("RangeSearchContext: got error %v, want context.Canceled", err)
+	}
+
+	// An uncanceled context behaves like the plain search
+	results, err = hnsw.KNNSearchContext(context.Background(), query, 10, 50)
+	if err != nil || len(results) != 10 {
+		t.Errorf("got %d results and error %v, want 10 results", len(results), err)
+	}
+}
+
+func TestKNNSearchWithOptionsBudget(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	for i := 1; i <= 500; i++ {
+		if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+
+	query := []float64{0.5, 0.5}
+	ctx := context.Background()
+
+	// Unbounded search reports its work without truncation
+	results, _, stats, err := hnsw.KNNSearchWithOptions(ctx, query, 10, algorithm.SearchOptions{Ef: 50})
+	if err != nil || len(results) != 10 {
+		t.Fatalf("got %d results and error %v, want 10 results", len(results), err)
+	}
+	if stats.Truncated || stats.DistanceComputations == 0 || stats.Hops == 0 {
+		t.Errorf("unexpected stats for unbounded search: %+v", stats)
+	}
+
+	tests := []struct {
+		name string
+		opts algorithm.SearchOptions
+	}{
+		{"Distance budget", algorithm.SearchOptions{Ef: 50, MaxDistanceComputations: 20}},
+		{"Visited budget", algorithm.SearchOptions{Ef: 50, MaxVisitedNodes: 15}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, distances, stats, err := hnsw.KNNSearchWithOptions(ctx, query, 10, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !stats.Truncated {
+				t.Error("expected search to be truncated")
+			}
+			if tt.opts.MaxDistanceComputations > 0 && stats.DistanceComputations > tt.opts.MaxDistanceComputations {
+				t.Errorf("got %d distance computations, budget %d", stats.DistanceComputations, tt.opts.MaxDistanceComputations)
+			}
+			if tt.opts.MaxVisitedNodes > 0 && stats.VisitedNodes > tt.opts.MaxVisitedNodes {
+				t.Errorf("visited %d nodes, budget %d", stats.VisitedNodes, tt.opts.MaxVisitedNodes)
+			}
+			if len(results) == 0 || len(results) != len(distances) {
+				t.Errorf("got %d results and %d distances", len(results), len(distances))
+			}
+			for i := 1; i < len(distances); i++ {
+				if distances[i] < distances[i-1] {
+					t.Error("distances not sorted")
+				}
+			}
+		})
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	for i := 1; i <= 50; i++ {
+		if err := hnsw.Insert(i, []float64{float64(i), 0}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+
+	query := []float64{0, 0}
+	ctx := context.Background()
+
+	t.Run("Filter", func(t *testing.T) {
+		even := func(id int) bool { return id%2 == 0 }
+		results, err := hnsw.Search(ctx, query, algorithm.SearchOptions{K: 5, Filter: even})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []int{2, 4, 6, 8, 10}
+		if len(results) != len(want) {
+			t.Fatalf("got %d results, want %d", len(results), len(want))
+		}
+		for i, result := range results {
+			if result.ID != want[i] {
+				t.Errorf("result %d: got id %d, want %d", i, result.ID, want[i])
+			}
+		}
+	})
+
+	t.Run("Distance threshold", func(t *testing.T) {
+		results, err := hnsw.Search(ctx, query, algorithm.SearchOptions{K: 10, MaxDistance: 3.5})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 3 {
+			t.Errorf("got %d results, want 3", len(results))
+		}
+	})
+
+	t.Run("Invalid K", func(t *testing.T) {
+		if _, err := hnsw.Search(ctx, query, algorithm.SearchOptions{}); err == nil {
+			t.Error("expected error for K = 0")
+		}
+	})
 }
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/node"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// buildSeeded inserts the same 500 vectors into an index with the given
+// seed and returns the saved graph
+func buildSeeded(t *testing.T, seed int64) (*algorithm.HNSW, map[int]*node.Node) {
+	t.Helper()
+
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM = 6, 12
+	cfg.Seed = seed
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+
+	filename := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := hnsw.Save(filename, ""); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	data, err := storage.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if data.Metadata.Seed != hnsw.Seed() {
+		t.Errorf("saved seed %d, index seed %d", data.Metadata.Seed, hnsw.Seed())
+	}
+	return hnsw, data.Nodes
+}
+
+func TestSeededConstruction(t *testing.T) {
+	a, graphA := buildSeeded(t, 42)
+	_, graphB := buildSeeded(t, 42)
+	if a.Seed() != 42 {
+		t.Errorf("got seed %d, want 42", a.Seed())
+	}
+	if !reflect.DeepEqual(graphA, graphB) {
+		t.Error("indexes built with the same seed differ")
+	}
+
+	_, graphC := buildSeeded(t, 43)
+	if reflect.DeepEqual(graphA, graphC) {
+		t.Error("indexes built with different seeds are identical")
+	}
+
+	// A zero seed is replaced by a random one, which is recorded
+	random, _ := buildSeeded(t, 0)
+	if random.Seed() == 0 {
+		t.Error("zero seed was not replaced")
+	}
+}
+
+func TestSeedSurvivesLoad(t *testing.T) {
+	hnsw, _ := buildSeeded(t, 7)
+
+	filename := filepath.Join(t.TempDir(), "index.json")
+	if err := hnsw.Save(filename, ""); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := algorithm.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if loaded.Seed() != 7 || loaded.Config().Seed != 7 {
+		t.Errorf("got seed %d and config seed %d, want 7", loaded.Seed(), loaded.Config().Seed)
+	}
+}
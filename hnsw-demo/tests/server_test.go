@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/collection"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/server"
+)
+
+// do sends a JSON request to the test server and decodes the response into out
+func do(t *testing.T, ts *httptest.Server, method, path string, body interface{}, out interface{}) int {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := collection.Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ts := httptest.NewServer(server.New(manager))
+	defer ts.Close()
+
+	create := map[string]interface{}{"name": "docs", "metric": "euclidean", "dimension": 2}
+	if status := do(t, ts, "POST", "/collections", create, nil); status != http.StatusCreated {
+		t.Fatalf("create: got status %d", status)
+	}
+	if status := do(t, ts, "POST", "/collections", create, nil); status != http.StatusConflict {
+		t.Errorf("duplicate create: got status %d, want 409", status)
+	}
+	bad := map[string]interface{}{"name": "bad", "metric": "no-such-metric", "dimension": 2}
+	if status := do(t, ts, "POST", "/collections", bad, nil); status != http.StatusBadRequest {
+		t.Errorf("unknown metric: got status %d, want 400", status)
+	}
+
+	// Batch insert with one bad element
+	batch := server.BatchRequest{}
+	for i := 1; i <= 20; i++ {
+		id := i
+		lang := "en"
+		if i%2 == 0 {
+			lang = "de"
+		}
+		batch.Vectors = append(batch.Vectors, server.VectorRequest{
+			ID:         &id,
+			Vector:     []float64{float64(i), 0},
+			Attributes: map[string]string{"lang": lang},
+		})
+	}
+	badID := 99
+	batch.Vectors = append(batch.Vectors, server.VectorRequest{ID: &badID, Vector: []float64{1, 2, 3}})
+
+	var batchResp server.BatchResponse
+	if status := do(t, ts, "POST", "/collections/docs/vectors/batch", batch, &batchResp); status != http.StatusOK {
+		t.Fatalf("batch: got status %d", status)
+	}
+	if batchResp.Inserted != 20 || len(batchResp.Errors) != 1 || batchResp.Errors[0].Status != http.StatusBadRequest {
+		t.Errorf("unexpected batch response %+v", batchResp)
+	}
+
+	one := 1
+	if status := do(t, ts, "POST", "/collections/docs/vectors", server.VectorRequest{ID: &one, Vector: []float64{0, 0}}, nil); status != http.StatusConflict {
+		t.Errorf("duplicate insert: got status %d, want 409", status)
+	}
+
+	// Upsert moves element 1 next to the origin
+	if status := do(t, ts, "PUT", "/collections/docs/vectors/1", server.VectorRequest{Vector: []float64{-1, 0}, Attributes: map[string]string{"lang": "en"}}, nil); status != http.StatusNoContent {
+		t.Errorf("upsert: got status %d", status)
+	}
+	var record server.RecordResponse
+	if status := do(t, ts, "GET", "/collections/docs/vectors/1", nil, &record); status != http.StatusOK || record.Vector[0] != -1 {
+		t.Errorf("get: got status %d, record %+v", status, record)
+	}
+
+	var searchResp server.SearchResponse
+	search := server.SearchRequest{Vector: []float64{0, 0}, K: 3, Filter: map[string]string{"lang": "de"}, IncludeAttributes: true}
+	if status := do(t, ts, "POST", "/collections/docs/search", search, &searchResp); status != http.StatusOK {
+		t.Fatalf("search: got status %d", status)
+	}
+	if len(searchResp.Results) != 3 || searchResp.Results[0].ID != 2 || searchResp.Results[0].Attributes["lang"] != "de" {
+		t.Errorf("unexpected search results %+v", searchResp.Results)
+	}
+
+	if status := do(t, ts, "POST", "/collections/docs/search", server.SearchRequest{Vector: []float64{0}, K: 3}, nil); status != http.StatusBadRequest {
+		t.Errorf("dimension mismatch: got status %d, want 400", status)
+	}
+
+	var rangeResp server.SearchResponse
+	if status := do(t, ts, "POST", "/collections/docs/range", server.RangeRequest{Vector: []float64{0, 0}, Radius: 4.5}, &rangeResp); status != http.StatusOK {
+		t.Fatalf("range: got status %d", status)
+	}
+	if len(rangeResp.Results) != 4 || rangeResp.Results[0].ID != 1 {
+		t.Errorf("unexpected range results %+v", rangeResp.Results)
+	}
+
+	if status := do(t, ts, "DELETE", "/collections/docs/vectors/2", nil, nil); status != http.StatusNoContent {
+		t.Errorf("delete: got status %d", status)
+	}
+	if status := do(t, ts, "DELETE", "/collections/docs/vectors/2", nil, nil); status != http.StatusNotFound {
+		t.Errorf("second delete: got status %d, want 404", status)
+	}
+
+	var tuneResp server.TuneResponse
+	tune := server.TuneRequest{Queries: [][]float64{{0, 0}, {5.5, 0}, {19, 1}}, K: 3, Recall: 1}
+	if status := do(t, ts, "POST", "/collections/docs/tune", tune, &tuneResp); status != http.StatusOK || tuneResp.Ef < 3 || tuneResp.Recall != 1 {
+		t.Errorf("tune: got status %d, response %+v", status, tuneResp)
+	}
+	if status := do(t, ts, "POST", "/collections/docs/tune", server.TuneRequest{Queries: tune.Queries, K: 3, Recall: 2}, nil); status != http.StatusBadRequest {
+		t.Errorf("tune with recall 2: got status %d, want 400", status)
+	}
+
+	var stats server.StatsResponse
+	if status := do(t, ts, "GET", "/collections/docs/stats", nil, &stats); status != http.StatusOK || stats.Count != 19 || len(stats.Levels) == 0 || stats.Levels[0].Nodes != 19 {
+		t.Errorf("stats: got status %d, stats %+v", status, stats)
+	}
+	if stats.DefaultEf != tuneResp.Ef {
+		t.Errorf("stats: got default ef %d, want the tuned %d", stats.DefaultEf, tuneResp.Ef)
+	}
+
+	if status := do(t, ts, "POST", "/collections/docs/snapshot", nil, nil); status != http.StatusNoContent {
+		t.Errorf("snapshot: got status %d", status)
+	}
+	if status := do(t, ts, "GET", "/collections/missing/stats", nil, nil); status != http.StatusNotFound {
+		t.Errorf("missing collection: got status %d, want 404", status)
+	}
+
+	// The snapshot survives a restart
+	reopened, err := collection.Open(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	c, err := reopened.Get("docs")
+	if err != nil || c.Len() != 19 {
+		t.Errorf("reopened collection: %v, %v", c, err)
+	} else if ef := c.Index().DefaultEf(); ef != tuneResp.Ef {
+		t.Errorf("reopened collection: got default ef %d, want %d", ef, tuneResp.Ef)
+	}
+
+	if status := do(t, ts, "DELETE", "/collections/docs", nil, nil); status != http.StatusNoContent {
+		t.Errorf("drop: got status %d", status)
+	}
+	var list server.ListCollectionsResponse
+	if status := do(t, ts, "GET", "/collections", nil, &list); status != http.StatusOK || len(list.Collections) != 0 {
+		t.Errorf("list after drop: %d, %s", status, fmt.Sprint(list.Collections))
+	}
+}
+
+func TestServerCreateErrors(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := collection.Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ts := httptest.NewServer(server.New(manager))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name string
+		req  map[string]interface{}
+	}{
+		{"bad name", map[string]interface{}{"name": "a/b", "metric": "euclidean", "dimension": 2}},
+		{"bad metric", map[string]interface{}{"name": "a", "metric": "no-such-metric", "dimension": 2}},
+		{"bad dimension", map[string]interface{}{"name": "a", "metric": "euclidean", "dimension": 0}},
+		{"bad config", map[string]interface{}{"name": "a", "metric": "euclidean", "dimension": 2, "config": map[string]interface{}{"m": -1}}},
+	} {
+		if status := do(t, ts, "POST", "/collections", tc.req, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", tc.name, status)
+		}
+	}
+
+	// A manifest that cannot be written is a server error, not bad input
+	if err := os.Mkdir(filepath.Join(dir, collection.ManifestFile+".tmp"), 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	create := map[string]interface{}{"name": "docs", "metric": "euclidean", "dimension": 2}
+	if status := do(t, ts, "POST", "/collections", create, nil); status != http.StatusInternalServerError {
+		t.Errorf("unwritable manifest: got status %d, want 500", status)
+	}
+}
+
+func TestServerContextErrors(t *testing.T) {
+	manager, err := collection.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	handler := server.New(manager)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	create := map[string]interface{}{"name": "docs", "metric": "euclidean", "dimension": 2}
+	if status := do(t, ts, "POST", "/collections", create, nil); status != http.StatusCreated {
+		t.Fatalf("create: got status %d", status)
+	}
+	batch := server.BatchRequest{}
+	for i := 1; i <= 20; i++ {
+		id := i
+		batch.Vectors = append(batch.Vectors, server.VectorRequest{ID: &id, Vector: []float64{float64(i), 0}})
+	}
+	if status := do(t, ts, "POST", "/collections/docs/vectors/batch", batch, nil); status != http.StatusOK {
+		t.Fatalf("batch insert: got status %d", status)
+	}
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"expired", expired, http.StatusGatewayTimeout},
+		{"cancelled", cancelled, 499},
+	} {
+		body, _ := json.Marshal(server.SearchRequest{Vector: []float64{3, 0}, K: 5})
+		req := httptest.NewRequest("POST", "/collections/docs/search", bytes.NewReader(body)).WithContext(tc.ctx)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s search: got status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
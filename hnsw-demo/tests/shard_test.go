@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/shard"
+)
+
+func TestShardedIndex(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM, cfg.EfConstruction = 8, 16, 60
+	cfg.Seed = 9
+	index, err := shard.New(4, cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(9))
+	exact, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	for i := 0; i < 2000; i++ {
+		v := randomVector(rng, 8)
+		if err := index.Insert(i, v); err != nil {
+			t.Fatalf("Insert %d failed: %v", i, err)
+		}
+		exact.Insert(i, v)
+	}
+	if index.Len() != 2000 {
+		t.Fatalf("got %d elements, want 2000", index.Len())
+	}
+	for i := 0; i < index.Shards(); i++ {
+		if n := index.Shard(i).Len(); n < 300 || n > 700 {
+			t.Errorf("shard %d holds %d of 2000 elements", i, n)
+		}
+	}
+	if _, err := index.Get(1234); err != nil {
+		t.Errorf("Get failed: %v", err)
+	}
+	if err := index.Insert(1, randomVector(rng, 8)); !errors.Is(err, algorithm.ErrNodeExists) {
+		t.Errorf("duplicate insert: got %v, want ErrNodeExists", err)
+	}
+	if err := index.Insert(5000, []float64{1, 2}); !errors.Is(err, algorithm.ErrDimensionMismatch) {
+		t.Errorf("short vector: got %v, want ErrDimensionMismatch", err)
+	}
+
+	// Merged results are sorted, come from every shard and match exact search
+	ctx := context.Background()
+	hits, total := 0, 0
+	for q := 0; q < 30; q++ {
+		query := randomVector(rng, 8)
+		results, stats, err := index.SearchWithStats(ctx, query, algorithm.SearchOptions{K: 10, Ef: 50})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 10 || stats.DistanceComputations == 0 {
+			t.Fatalf("got %d results and %+v", len(results), stats)
+		}
+		truth, _ := exact.ExactSearch(ctx, query, 10)
+		want := make(map[int]bool)
+		for _, r := range truth {
+			want[r.ID] = true
+		}
+		for i, r := range results {
+			if i > 0 && r.Distance < results[i-1].Distance {
+				t.Fatalf("results out of order: %+v", results)
+			}
+			if want[r.ID] {
+				hits++
+			}
+			total++
+		}
+	}
+	if recall := float64(hits) / float64(total); recall < 0.95 {
+		t.Errorf("recall@10 %.3f, want at least 0.95", recall)
+	}
+
+	if err := index.Delete(1234); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if _, err := index.Get(1234); !errors.Is(err, algorithm.ErrNodeNotFound) {
+		t.Errorf("Get after delete: got %v, want ErrNodeNotFound", err)
+	}
+
+	// Save and reload the whole index, then one shard on its own
+	dir := t.TempDir()
+	if err := index.Save(dir, "sharded"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := shard.Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Shards() != 4 || loaded.Len() != 1999 || loaded.Dimension() != 8 {
+		t.Fatalf("loaded %d shards with %d elements of dimension %d", loaded.Shards(), loaded.Len(), loaded.Dimension())
+	}
+
+	filename := filepath.Join(t.TempDir(), "one.hnsw")
+	if err := index.SaveShard(2, filename, ""); err != nil {
+		t.Fatalf("SaveShard failed: %v", err)
+	}
+	fresh, err := shard.New(4, cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fresh.LoadShard(2, filename); err != nil {
+		t.Fatalf("LoadShard failed: %v", err)
+	}
+	if fresh.Len() != index.Shard(2).Len() || fresh.Dimension() != 8 {
+		t.Errorf("got %d elements of dimension %d after loading shard 2", fresh.Len(), fresh.Dimension())
+	}
+
+	other, err := shard.New(4, cfg, distance.Cosine)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := other.LoadShard(0, filename); err == nil {
+		t.Error("loading a euclidean shard into a cosine index succeeded")
+	}
+	if _, err := shard.Load(t.TempDir()); err == nil {
+		t.Error("loading a directory without shards succeeded")
+	}
+}
+
+// TestShardedIndexConcurrent searches and reads while inserting; run it
+// with -race to check the shard locking
+func TestShardedIndexConcurrent(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM, cfg.EfConstruction = 8, 16, 40
+	index, err := shard.New(2, cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// Searching an empty index checks K and returns no results
+	if _, err := index.Search(ctx, []float64{0, 0}, algorithm.SearchOptions{K: 0}); err == nil {
+		t.Error("search with K 0 succeeded")
+	}
+	if results, err := index.Search(ctx, []float64{0, 0}, algorithm.SearchOptions{K: 5}); err != nil || results == nil || len(results) != 0 {
+		t.Errorf("empty search: got %v, %v, want an empty slice", results, err)
+	}
+
+	rng := rand.New(rand.NewSource(4))
+	vectors := make([][]float64, 600)
+	for i := range vectors {
+		vectors[i] = randomVector(rng, 4)
+	}
+	for i := 0; i < 100; i++ {
+		if err := index.Insert(i, vectors[i]); err != nil {
+			t.Fatalf("Insert %d failed: %v", i, err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 100; i < len(vectors); i++ {
+			if err := index.Insert(i, vectors[i]); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for q := 0; ; q++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("concurrent Insert failed: %v", err)
+			}
+		default:
+			if _, err := index.Search(ctx, vectors[q%len(vectors)], algorithm.SearchOptions{K: 5}); err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if _, err := index.Get(q % 100); err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			continue
+		}
+		break
+	}
+	if index.Len() != len(vectors) {
+		t.Errorf("got %d elements, want %d", index.Len(), len(vectors))
+	}
+}
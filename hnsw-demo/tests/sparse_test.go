@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/sparse"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+// randomSparse returns a sparse vector with nnz entries over a vocabulary
+func randomSparse(rng *rand.Rand, vocabulary, nnz int) sparse.Vector {
+	m := make(map[int]float64, nnz)
+	for len(m) < nnz {
+		m[rng.Intn(vocabulary)] = rng.Float64() + 0.1
+	}
+	v, _ := sparse.FromMap(m)
+	return v
+}
+
+func TestSparseVector(t *testing.T) {
+	v, err := sparse.NewVector([]int{5, 1, 3}, []float64{0.5, 0.1, 0.3})
+	if err != nil {
+		t.Fatalf("NewVector failed: %v", err)
+	}
+	if v.Indices[0] != 1 || v.Values[0] != 0.1 || v.Indices[2] != 5 {
+		t.Errorf("expected sorted vector, got %+v", v)
+	}
+
+	w, _ := sparse.NewVector([]int{3, 5, 7}, []float64{2, 4, 8})
+	if got := sparse.Dot(v, w); got != 0.3*2+0.5*4 {
+		t.Errorf("got dot %f, want %f", got, 0.3*2+0.5*4)
+	}
+
+	if _, err := sparse.NewVector([]int{1, 1}, []float64{1, 2}); err == nil {
+		t.Error("expected error for duplicate indices")
+	}
+	if _, err := sparse.NewVector([]int{1}, []float64{1, 2}); err == nil {
+		t.Error("expected error for length mismatch")
+	}
+}
+
+func TestSparseIndex(t *testing.T) {
+	for _, metric := range []string{distance.Cosine, distance.DotProduct} {
+		t.Run(metric, func(t *testing.T) {
+			hnsw, err := algorithm.NewSparse(config.NewDefaultConfig(), metric)
+			if err != nil {
+				t.Fatalf("Failed to create sparse HNSW: %v", err)
+			}
+
+			rng := rand.New(rand.NewSource(6))
+			vectors := make(map[int]sparse.Vector)
+			for id := 1; id <= 300; id++ {
+				vectors[id] = randomSparse(rng, 200, 10)
+				if err := hnsw.InsertSparse(id, vectors[id]); err != nil {
+					t.Fatalf("InsertSparse failed: %v", err)
+				}
+			}
+
+			distFunc, _ := sparse.GetDistanceFunction(metric)
+			hits, total := 0, 0
+			for trial := 0; trial < 20; trial++ {
+				q := randomSparse(rng, 200, 10)
+
+				// Brute-force ground truth
+				ids := make([]int, 0, len(vectors))
+				for id := range vectors {
+					ids = append(ids, id)
+				}
+				sort.Slice(ids, func(i, j int) bool {
+					return distFunc(q, vectors[ids[i]]) < distFunc(q, vectors[ids[j]])
+				})
+				truth := make(map[int]bool)
+				for _, id := range ids[:10] {
+					truth[id] = true
+				}
+
+				results, err := hnsw.SearchSparse(context.Background(), q, algorithm.SearchOptions{K: 10, Ef: 100})
+				if err != nil {
+					t.Fatalf("SearchSparse failed: %v", err)
+				}
+				for _, r := range results {
+					if truth[r.ID] {
+						hits++
+					}
+				}
+				total += 10
+			}
+			if recall := float64(hits) / float64(total); recall < 0.9 {
+				t.Errorf("recall %.2f below 0.9", recall)
+			}
+		})
+	}
+}
+
+func TestSparseIndexErrorsAndPersistence(t *testing.T) {
+	if _, err := algorithm.NewSparse(config.NewDefaultConfig(), distance.Euclidean); err == nil {
+		t.Error("expected error for metric without sparse support")
+	}
+
+	hnsw, err := algorithm.NewSparse(config.NewDefaultConfig(), distance.Cosine)
+	if err != nil {
+		t.Fatalf("Failed to create sparse HNSW: %v", err)
+	}
+	if err := hnsw.Insert(1, []float64{1, 2}); !errors.Is(err, algorithm.ErrSparseIndex) {
+		t.Errorf("got %v, want ErrSparseIndex", err)
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	for id := 1; id <= 50; id++ {
+		if err := hnsw.InsertSparse(id, randomSparse(rng, 100, 5)); err != nil {
+			t.Fatalf("InsertSparse failed: %v", err)
+		}
+	}
+
+	record, err := hnsw.Get(7)
+	if err != nil || record.Sparse.Len() != 5 {
+		t.Fatalf("Get returned %+v, %v", record, err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "sparse.hnsw")
+	if err := hnsw.Save(filename, ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := algorithm.Load(filename)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !loaded.IsSparse() {
+		t.Fatal("loaded index should be sparse")
+	}
+
+	results, err := loaded.SearchSparse(context.Background(), record.Sparse,
+		algorithm.SearchOptions{K: 1, Ef: 50, IncludeVectors: true})
+	if err != nil || len(results) != 1 || results[0].ID != 7 || results[0].Sparse.Len() != 5 {
+		t.Errorf("expected to find the stored vector itself, got %+v, %v", results, err)
+	}
+
+	dense, _ := algorithm.New(config.NewDefaultConfig(), distance.Cosine)
+	if err := dense.InsertSparse(1, record.Sparse); !errors.Is(err, algorithm.ErrDenseIndex) {
+		t.Errorf("got %v, want ErrDenseIndex", err)
+	}
+}
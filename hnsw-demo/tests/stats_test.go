@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestStats(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	if stats := hnsw.Stats(); stats.Nodes != 0 || len(stats.Levels) != 0 || stats.MemoryBytes != 0 {
+		t.Errorf("empty index: got %+v", stats)
+	}
+
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 400; i++ {
+		err := hnsw.InsertWithAttributes(i, []float64{rng.Float64(), rng.Float64(), rng.Float64(), rng.Float64()},
+			map[string]string{"tag": "x"})
+		if err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 40; i++ {
+		if err := hnsw.Delete(i); err != nil {
+			t.Fatalf("Failed to delete %d: %v", i, err)
+		}
+	}
+
+	stats := hnsw.Stats()
+	if stats.Nodes != 360 || stats.Deleted != 40 {
+		t.Errorf("got %d nodes and %d deleted, want 360 and 40", stats.Nodes, stats.Deleted)
+	}
+	if stats.MaxLevel != hnsw.MaxLevel() || len(stats.Levels) != stats.MaxLevel+1 {
+		t.Errorf("got max level %d with %d levels, want %d", stats.MaxLevel, len(stats.Levels), hnsw.MaxLevel())
+	}
+	if stats.Unreachable != 0 {
+		t.Errorf("got %d unreachable nodes, want 0", stats.Unreachable)
+	}
+	// At least the vectors themselves
+	if stats.MemoryBytes < 360*4*8 {
+		t.Errorf("memory estimate %d is too small", stats.MemoryBytes)
+	}
+
+	if got := stats.Levels[0].Nodes; got != stats.Nodes {
+		t.Errorf("level 0 has %d nodes, want %d", got, stats.Nodes)
+	}
+	for level, ls := range stats.Levels {
+		if level > 0 && ls.Nodes > stats.Levels[level-1].Nodes {
+			t.Errorf("level %d has more nodes than the level below", level)
+		}
+		if ls.Nodes == 0 {
+			continue
+		}
+
+		maxM := cfg.M
+		if level == 0 {
+			maxM = cfg.MaxM
+		}
+		if ls.MinDegree > ls.MaxDegree || ls.MaxDegree > maxM ||
+			ls.MeanDegree < float64(ls.MinDegree) || ls.MeanDegree > float64(ls.MaxDegree) {
+			t.Errorf("level %d: inconsistent degrees %+v", level, ls)
+		}
+
+		counted := 0
+		for degree, count := range ls.Histogram {
+			if degree < ls.MinDegree || degree > ls.MaxDegree {
+				t.Errorf("level %d: degree %d outside [%d, %d]", level, degree, ls.MinDegree, ls.MaxDegree)
+			}
+			counted += count
+		}
+		if counted != ls.Nodes {
+			t.Errorf("level %d: histogram counts %d nodes, want %d", level, counted, ls.Nodes)
+		}
+	}
+}
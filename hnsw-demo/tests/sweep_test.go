@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/sweep"
+)
+
+func TestSweepGrid(t *testing.T) {
+	space := sweep.Space{M: []int{4, 8}, EfConstruction: []int{50, 100, 200}}
+	grid := sweep.Grid(space)
+	if len(grid) != 6 {
+		t.Fatalf("got %d combinations, want 6", len(grid))
+	}
+	for _, p := range grid {
+		if p.MaxM != 2*p.M {
+			t.Errorf("%v: MaxM should default to 2*M", p)
+		}
+	}
+
+	// Combinations with MaxM < M are skipped
+	space.MaxM = []int{6, 12}
+	if got := len(sweep.Grid(space)); got != 9 {
+		t.Errorf("got %d combinations, want 9", got)
+	}
+
+	random := sweep.Random(space, 4, rand.New(rand.NewSource(1)))
+	again := sweep.Random(space, 4, rand.New(rand.NewSource(1)))
+	if len(random) != 4 {
+		t.Fatalf("got %d random combinations, want 4", len(random))
+	}
+	seen := make(map[sweep.Params]bool)
+	for i, p := range random {
+		if seen[p] {
+			t.Errorf("combination %v drawn twice", p)
+		}
+		seen[p] = true
+		if p != again[i] {
+			t.Errorf("same seed drew %v and %v", p, again[i])
+		}
+	}
+	if got := len(sweep.Random(space, 100, rand.New(rand.NewSource(1)))); got != 9 {
+		t.Errorf("got %d random combinations, want the whole grid of 9", got)
+	}
+}
+
+func TestSweepRun(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	vectors := make([][]float64, 600)
+	for i := range vectors {
+		vectors[i] = []float64{rng.Float64(), rng.Float64(), rng.Float64(), rng.Float64()}
+	}
+
+	params := sweep.Grid(sweep.Space{M: []int{2, 12}, EfConstruction: []int{10, 100}})
+	results, err := sweep.Run(context.Background(), vectors[50:], vectors[:50], params, sweep.Options{
+		Metric: distance.Euclidean,
+		K:      5,
+		Seed:   1,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != len(params) {
+		t.Fatalf("got %d results, want %d", len(results), len(params))
+	}
+	for i, r := range results {
+		if r.Params != params[i] || r.BuildTime <= 0 || r.MemoryBytes <= 0 || r.QPS <= 0 || r.Recall < 0 || r.Recall > 1 {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+	// More connections and a larger construction list find more neighbors
+	if results[3].Recall < results[0].Recall || results[3].Recall < 0.9 {
+		t.Errorf("recall %.3f with %v, %.3f with %v", results[0].Recall, results[0].Params, results[3].Recall, results[3].Params)
+	}
+
+	if _, err := sweep.Run(context.Background(), vectors, vectors, params, sweep.Options{Metric: distance.Euclidean}); err == nil {
+		t.Error("Run without K succeeded")
+	}
+}
+
+func TestSweepFrontier(t *testing.T) {
+	result := func(m int, recall, qps float64, build time.Duration, memory int64) sweep.Result {
+		return sweep.Result{Params: sweep.Params{M: m}, Recall: recall, QPS: qps, BuildTime: build, MemoryBytes: memory}
+	}
+	results := []sweep.Result{
+		result(1, 0.90, 1000, time.Second, 100),
+		result(2, 0.99, 500, 2*time.Second, 200),
+		result(3, 0.89, 900, 2*time.Second, 200), // dominated by 1
+		result(4, 0.99, 500, 2*time.Second, 200), // ties with 2, neither dominates
+		result(5, 0.95, 400, 3*time.Second, 150), // worse than 2 except memory
+		result(6, 0.95, 400, 3*time.Second, 250), // dominated by 2
+	}
+
+	frontier := sweep.Frontier(results)
+	var ms []int
+	for _, r := range frontier {
+		ms = append(ms, r.Params.M)
+	}
+	want := []int{2, 4, 5, 1}
+	if len(ms) != len(want) {
+		t.Fatalf("got frontier %v, want %v", ms, want)
+	}
+	for i := range want {
+		if ms[i] != want[i] {
+			t.Fatalf("got frontier %v, want %v", ms, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestTuneEf(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM, cfg.EfConstruction = 4, 8, 20
+	cfg.Seed = 1
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(4))
+	randomVector := func() []float64 {
+		v := make([]float64, 8)
+		for i := range v {
+			v[i] = rng.Float64()
+		}
+		return v
+	}
+	for i := 0; i < 2000; i++ {
+		if err := hnsw.Insert(i, randomVector()); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+	queries := make([][]float64, 50)
+	for i := range queries {
+		queries[i] = randomVector()
+	}
+
+	ctx := context.Background()
+	tuning, err := hnsw.TuneEf(ctx, queries, 10, 0.95)
+	if err != nil {
+		t.Fatalf("TuneEf failed: %v", err)
+	}
+	if tuning.Ef < 10 || tuning.Recall < 0.95 || hnsw.DefaultEf() != tuning.Ef {
+		t.Errorf("got ef %d with recall %.3f and default ef %d", tuning.Ef, tuning.Recall, hnsw.DefaultEf())
+	}
+	for _, trial := range tuning.Trials {
+		if trial.Ef == tuning.Ef-1 && trial.Recall >= 0.95 {
+			t.Errorf("ef %d also reaches the target: %+v", trial.Ef, tuning.Trials)
+		}
+	}
+
+	// Searches without an ef use the tuned one
+	_, implicit, err := hnsw.SearchWithStats(ctx, queries[0], algorithm.SearchOptions{K: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	_, explicit, _ := hnsw.SearchWithStats(ctx, queries[0], algorithm.SearchOptions{K: 10, Ef: tuning.Ef})
+	if implicit != explicit {
+		t.Errorf("default search did %+v, search with ef %d did %+v", implicit, tuning.Ef, explicit)
+	}
+
+	// The sample recall helpers reproduce the tuned recall
+	truth, err := hnsw.GroundTruth(ctx, queries, 10)
+	if err != nil {
+		t.Fatalf("GroundTruth failed: %v", err)
+	}
+	recall, elapsed, err := hnsw.SampleRecall(ctx, queries, truth, algorithm.SearchOptions{K: 10, Ef: tuning.Ef})
+	if err != nil || recall != tuning.Recall || elapsed <= 0 {
+		t.Errorf("SampleRecall: got %.3f in %v, %v, want %.3f", recall, elapsed, err, tuning.Recall)
+	}
+	if _, _, err := hnsw.SampleRecall(ctx, queries, truth[:1], algorithm.SearchOptions{K: 10}); err == nil {
+		t.Error("SampleRecall with truth for too few queries succeeded")
+	}
+
+	// The default ef is saved with the index
+	filename := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := hnsw.Save(filename, ""); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := algorithm.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if loaded.DefaultEf() != tuning.Ef {
+		t.Errorf("loaded default ef %d, want %d", loaded.DefaultEf(), tuning.Ef)
+	}
+
+	// Invalid arguments leave the default alone
+	for _, tc := range []struct {
+		queries [][]float64
+		k       int
+		recall  float64
+	}{
+		{nil, 10, 0.9},
+		{queries, 0, 0.9},
+		{queries, 10, 0},
+		{queries, 10, 1.5},
+		{[][]float64{{1, 2}}, 10, 0.9},
+	} {
+		if _, err := hnsw.TuneEf(ctx, tc.queries, tc.k, tc.recall); err == nil {
+			t.Errorf("TuneEf(%d queries, %d, %g) succeeded", len(tc.queries), tc.k, tc.recall)
+		}
+	}
+	if hnsw.DefaultEf() != tuning.Ef {
+		t.Errorf("default ef changed to %d", hnsw.DefaultEf())
+	}
+}
+
+func TestTuneEfUnreachable(t *testing.T) {
+	// Without reconnection, deletions strand elements that no ef finds
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM = 2, 4
+	cfg.DelayRebuild = true
+	cfg.Seed = 3
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	rng := rand.New(rand.NewSource(5))
+	var vectors [][]float64
+	for i := 0; i < 300; i++ {
+		v := []float64{rng.Float64(), rng.Float64()}
+		vectors = append(vectors, v)
+		if err := hnsw.Insert(i, v); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 300; i += 2 {
+		if err := hnsw.Delete(i); err != nil {
+			t.Fatalf("Failed to delete %d: %v", i, err)
+		}
+	}
+	if hnsw.Stats().Unreachable == 0 {
+		t.Skip("deletions left every element reachable")
+	}
+
+	// Query every stranded element for itself
+	var queries [][]float64
+	for i := 1; i < 300; i += 2 {
+		queries = append(queries, vectors[i])
+	}
+	tuning, err := hnsw.TuneEf(context.Background(), queries, 1, 1)
+	if !errors.Is(err, algorithm.ErrRecallUnreachable) {
+		t.Fatalf("got %+v, %v, want ErrRecallUnreachable", tuning, err)
+	}
+	if hnsw.DefaultEf() != 0 || len(tuning.Trials) == 0 {
+		t.Errorf("got default ef %d and trials %+v", hnsw.DefaultEf(), tuning.Trials)
+	}
+}
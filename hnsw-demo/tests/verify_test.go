@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/config"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/distance"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/pkg/storage"
+	"github.com/fyerfyer/nearest-neighbour-search/hnsw-demo/src/algorithm"
+)
+
+func TestVerify(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.M, cfg.MaxM = 4, 8
+	hnsw, err := algorithm.New(cfg, distance.Euclidean)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 500; i++ {
+		if err := hnsw.Insert(i, []float64{rng.Float64(), rng.Float64(), rng.Float64()}); err != nil {
+			t.Fatalf("Failed to insert vector %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 500; i += 3 {
+		if err := hnsw.Delete(i); err != nil {
+			t.Fatalf("Failed to delete %d: %v", i, err)
+		}
+	}
+
+	// Pruning can drop the only in-edge of a node, so reachability is only
+	// guaranteed after Repair. Insert and Delete leave no invalid edge or
+	// misplaced entry point for it to fix, and keep degrees within MaxM
+	// and M
+	repair := hnsw.Repair()
+	if repair.RemovedEdges != 0 || repair.EntryPointMoved || repair.Remaining != 0 {
+		t.Fatalf("unexpected repair of the built index: %s", repair)
+	}
+	report := hnsw.Verify()
+	if !report.OK() {
+		t.Fatalf("built index has problems:\n%s", report)
+	}
+	if report.Nodes != hnsw.Len() || report.Edges == 0 {
+		t.Errorf("got %d nodes and %d edges, want %d nodes and some edges", report.Nodes, report.Edges, hnsw.Len())
+	}
+
+	// Corrupt a saved copy of the graph
+	filename := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := hnsw.Save(filename, ""); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	data, err := storage.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	n := data.Nodes[1]
+	neighbors := n.Neighbors[0]
+	n.Neighbors[0] = append([]int{1, 9999, neighbors[0]}, neighbors...)
+	n.Neighbors[n.Level+1] = []int{2}
+	for id := 2; len(n.Neighbors[0]) <= cfg.MaxM; id++ {
+		if _, exists := data.Nodes[id]; exists && id%3 != 0 {
+			n.Neighbors[0] = append(n.Neighbors[0], id)
+		}
+	}
+
+	// Isolate node 4 on layer 0
+	isolated := 4
+	data.Nodes[isolated].Neighbors[0] = nil
+	for _, other := range data.Nodes {
+		kept := other.Neighbors[0][:0]
+		for _, id := range other.Neighbors[0] {
+			if id != isolated {
+				kept = append(kept, id)
+			}
+		}
+		other.Neighbors[0] = kept
+	}
+
+	// An entry point below the top level
+	for id, other := range data.Nodes {
+		if id != isolated && other.Level < data.Metadata.MaxLevel {
+			data.EntryPoint = id
+			break
+		}
+	}
+
+	if err := storage.Save(filename, data); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	corrupted, err := algorithm.Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	report = corrupted.Verify()
+	for _, kind := range []algorithm.ProblemKind{
+		algorithm.ProblemSelfLoop,
+		algorithm.ProblemMissingNeighbor,
+		algorithm.ProblemDuplicateNeighbor,
+		algorithm.ProblemLevelMismatch,
+		algorithm.ProblemDegreeExceeded,
+		algorithm.ProblemEntryPoint,
+		algorithm.ProblemUnreachable,
+	} {
+		if report.Count(kind) == 0 {
+			t.Errorf("no %s problem reported:\n%s", kind, report)
+		}
+	}
+
+	found := false
+	for _, p := range report.Problems {
+		if p.Kind == algorithm.ProblemUnreachable && p.NodeID == isolated && p.Level == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("node %d not reported unreachable on level 0:\n%s", isolated, report)
+	}
+}